@@ -0,0 +1,362 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqttclient "github.com/LanSilence/hamqtt/pkg/mqtt"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"gopkg.in/yaml.v3"
+)
+
+// Config hamqtt 配置文件，支持 YAML 和 JSON（JSON 是 YAML 的子集）
+type Config struct {
+	MQTT struct {
+		Server             string `yaml:"server"`
+		Port               string `yaml:"port"`
+		User               string `yaml:"user"`
+		Pass               string `yaml:"pass"`
+		ClientID           string `yaml:"client_id"`
+		CAFile             string `yaml:"ca_file"`
+		CertFile           string `yaml:"cert_file"`
+		KeyFile            string `yaml:"key_file"`
+		ServerName         string `yaml:"server_name"`
+		InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	} `yaml:"mqtt"`
+
+	Device struct {
+		Name         string `yaml:"name"`
+		Manufacturer string `yaml:"manufacturer"`
+		Model        string `yaml:"model"`
+		SwVersion    string `yaml:"sw_version"`
+	} `yaml:"device"`
+
+	Interval         time.Duration `yaml:"interval"`
+	BirthTopic       string        `yaml:"birth_topic"`
+	MaxAnnounceDelay time.Duration `yaml:"max_announce_delay"`
+	DiscoveryMode    string        `yaml:"discovery_mode"` // entity(默认) 或 device
+
+	// 主题布局
+	Topics struct {
+		DiscoveryPrefix string `yaml:"discovery_prefix"` // 默认 homeassistant
+		Base            string `yaml:"base"`             // 状态/命令主题前缀，如 hamqtt
+		NodeID          string `yaml:"node_id"`          // 节点ID模板，支持 {hostname} {client_id}
+	} `yaml:"topics"`
+
+	// 按名称配置内置采集器，未列出的采集器默认启用
+	Collectors map[string]CollectorConfig `yaml:"collectors"`
+	// 按实体名称配置发布策略
+	Policies map[string]PolicyConfig `yaml:"policies"`
+
+	// disks 采集器的筛选条件
+	Disks struct {
+		IncludeMounts  []string `yaml:"include_mounts"`
+		ExcludeMounts  []string `yaml:"exclude_mounts"`
+		IncludeFSTypes []string `yaml:"include_fstypes"`
+		ExcludeFSTypes []string `yaml:"exclude_fstypes"`
+	} `yaml:"disks"`
+	// diskio 采集器的块设备筛选
+	DiskIO struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+	} `yaml:"diskio"`
+	// network 采集器的接口筛选
+	Network struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+	} `yaml:"network"`
+	// hwmon 采集器使用的 sysfs 根目录，默认 /sys
+	HwmonRoot string `yaml:"hwmon_root"`
+
+	Power struct {
+		Action  string `yaml:"action"`  // suspend, shutdown, reboot, command, none
+		Command string `yaml:"command"` // action 为 command 时执行
+	} `yaml:"power"`
+
+	Commands []CommandConfig `yaml:"commands"`
+
+	// 自更新，source 为发布目录或清单文件，为空时不注册 update 实体
+	Update struct {
+		Source   string        `yaml:"source"`
+		Interval time.Duration `yaml:"interval"` // 检查新版本的间隔，默认10分钟
+	} `yaml:"update"`
+}
+
+// CollectorConfig 内置采集器配置
+type CollectorConfig struct {
+	Enabled  *bool         `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// PolicyConfig 实体发布策略配置
+type PolicyConfig struct {
+	Deadband        float64       `yaml:"deadband"`
+	DeadbandPercent float64       `yaml:"deadband_percent"`
+	Heartbeat       time.Duration `yaml:"heartbeat"`
+}
+
+// CommandConfig 执行本地命令的自定义实体
+// button 按下时执行 Command；switch 打开/关闭分别执行 OnCommand/OffCommand，
+// StateCommand 退出码为0表示开启
+type CommandConfig struct {
+	Name         string        `yaml:"name"`
+	Description  string        `yaml:"description"`
+	Component    string        `yaml:"component"` // button(默认) 或 switch
+	Command      string        `yaml:"command"`
+	OnCommand    string        `yaml:"on_command"`
+	OffCommand   string        `yaml:"off_command"`
+	StateCommand string        `yaml:"state_command"`
+	Interval     time.Duration `yaml:"interval"`
+}
+
+func defaultConfig() *Config {
+	cfg := &Config{}
+	cfg.MQTT.Server = "tcp://localhost"
+	cfg.MQTT.Port = "1883"
+	cfg.MQTT.ClientID = "hamqtt-client"
+	cfg.Interval = mqttclient.DefaultPublishInterval
+	return cfg
+}
+
+// loadConfig 读取配置文件，path 为空时返回默认配置
+func loadConfig(path string) (*Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	for i, cmd := range cfg.Commands {
+		if cmd.Name == "" {
+			return nil, fmt.Errorf("commands[%d] 缺少 name", i)
+		}
+		switch cmd.Component {
+		case "", "button", "switch":
+		default:
+			return nil, fmt.Errorf("commands[%d] 不支持的组件类型: %s", i, cmd.Component)
+		}
+	}
+	return cfg, nil
+}
+
+// applyEnv 使用 HAMQTT_* 环境变量覆盖配置文件
+func (cfg *Config) applyEnv() error {
+	strVars := map[string]*string{
+		"HAMQTT_SERVER":           &cfg.MQTT.Server,
+		"HAMQTT_PORT":             &cfg.MQTT.Port,
+		"HAMQTT_USER":             &cfg.MQTT.User,
+		"HAMQTT_PASS":             &cfg.MQTT.Pass,
+		"HAMQTT_CLIENT_ID":        &cfg.MQTT.ClientID,
+		"HAMQTT_CA_FILE":          &cfg.MQTT.CAFile,
+		"HAMQTT_CERT_FILE":        &cfg.MQTT.CertFile,
+		"HAMQTT_KEY_FILE":         &cfg.MQTT.KeyFile,
+		"HAMQTT_TLS_SERVER_NAME":  &cfg.MQTT.ServerName,
+		"HAMQTT_DEVICE_NAME":      &cfg.Device.Name,
+		"HAMQTT_POWER_ACTION":     &cfg.Power.Action,
+		"HAMQTT_POWER_COMMAND":    &cfg.Power.Command,
+		"HAMQTT_UPDATE_SOURCE":    &cfg.Update.Source,
+		"HAMQTT_DISCOVERY_MODE":   &cfg.DiscoveryMode,
+		"HAMQTT_DISCOVERY_PREFIX": &cfg.Topics.DiscoveryPrefix,
+		"HAMQTT_BASE_TOPIC":       &cfg.Topics.Base,
+		"HAMQTT_NODE_ID":          &cfg.Topics.NodeID,
+		"HAMQTT_HWMON_ROOT":       &cfg.HwmonRoot,
+	}
+	for name, field := range strVars {
+		if v, ok := os.LookupEnv(name); ok {
+			*field = v
+		}
+	}
+	if v, ok := os.LookupEnv("HAMQTT_TLS_INSECURE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("HAMQTT_TLS_INSECURE: %w", err)
+		}
+		cfg.MQTT.InsecureSkipVerify = b
+	}
+	if v, ok := os.LookupEnv("HAMQTT_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("HAMQTT_INTERVAL: %w", err)
+		}
+		cfg.Interval = d
+	}
+	return nil
+}
+
+// applyFlags 使用命令行中显式设置的参数覆盖配置
+func (cfg *Config) applyFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch f.Name {
+		case "server":
+			cfg.MQTT.Server = value
+		case "port":
+			cfg.MQTT.Port = value
+		case "user":
+			cfg.MQTT.User = value
+		case "pass":
+			cfg.MQTT.Pass = value
+		case "client-id":
+			cfg.MQTT.ClientID = value
+		case "ca-file":
+			cfg.MQTT.CAFile = value
+		case "cert-file":
+			cfg.MQTT.CertFile = value
+		case "key-file":
+			cfg.MQTT.KeyFile = value
+		case "tls-server-name":
+			cfg.MQTT.ServerName = value
+		case "tls-insecure":
+			cfg.MQTT.InsecureSkipVerify = value == "true"
+		case "interval":
+			cfg.Interval, _ = time.ParseDuration(value)
+		}
+	})
+}
+
+// toMQTTConfig 转换为客户端配置
+func (cfg *Config) toMQTTConfig() mqttclient.MQTTConfig {
+	mc := mqttclient.MQTTConfig{
+		Server:   cfg.MQTT.Server,
+		Port:     cfg.MQTT.Port,
+		User:     cfg.MQTT.User,
+		Pass:     cfg.MQTT.Pass,
+		ClientID: cfg.MQTT.ClientID,
+
+		CAFile:             cfg.MQTT.CAFile,
+		CertFile:           cfg.MQTT.CertFile,
+		KeyFile:            cfg.MQTT.KeyFile,
+		ServerName:         cfg.MQTT.ServerName,
+		InsecureSkipVerify: cfg.MQTT.InsecureSkipVerify,
+
+		PublishInterval:    cfg.Interval,
+		CollectorIntervals: map[string]time.Duration{},
+		PublishPolicies:    map[string]*mqttclient.PublishPolicy{},
+
+		Device: mqttclient.Device{
+			Name:         cfg.Device.Name,
+			Manufacturer: cfg.Device.Manufacturer,
+			Model:        cfg.Device.Model,
+			SwVersion:    cfg.Device.SwVersion,
+		},
+		PowerAction:  cfg.Power.Action,
+		PowerCommand: cfg.Power.Command,
+
+		DiskFilter: mqttclient.DiskFilter{
+			IncludeMounts:  cfg.Disks.IncludeMounts,
+			ExcludeMounts:  cfg.Disks.ExcludeMounts,
+			IncludeFSTypes: cfg.Disks.IncludeFSTypes,
+			ExcludeFSTypes: cfg.Disks.ExcludeFSTypes,
+		},
+
+		HwmonRoot: cfg.HwmonRoot,
+		DiskIOFilter: mqttclient.DiskIOFilter{
+			Include: cfg.DiskIO.Include,
+			Exclude: cfg.DiskIO.Exclude,
+		},
+		NetworkFilter: mqttclient.NetworkFilter{
+			Include: cfg.Network.Include,
+			Exclude: cfg.Network.Exclude,
+		},
+
+		BirthTopic:       cfg.BirthTopic,
+		MaxAnnounceDelay: cfg.MaxAnnounceDelay,
+		DiscoveryMode:    cfg.DiscoveryMode,
+		Version:          version,
+
+		DiscoveryPrefix: cfg.Topics.DiscoveryPrefix,
+		BaseTopic:       cfg.Topics.Base,
+		NodeID:          cfg.Topics.NodeID,
+	}
+	if mc.Device.SwVersion == "" {
+		mc.Device.SwVersion = version
+	}
+	for name, col := range cfg.Collectors {
+		if col.Enabled != nil && !*col.Enabled {
+			mc.DisabledCollectors = append(mc.DisabledCollectors, name)
+		}
+		if col.Interval > 0 {
+			mc.CollectorIntervals[name] = col.Interval
+		}
+	}
+	for name, p := range cfg.Policies {
+		mc.PublishPolicies[name] = &mqttclient.PublishPolicy{
+			Deadband:        p.Deadband,
+			DeadbandPercent: p.DeadbandPercent,
+			Heartbeat:       p.Heartbeat,
+		}
+	}
+	return mc
+}
+
+// registerCommands 注册配置文件中的自定义命令实体
+func registerCommands(client *mqttclient.MQTTClient, commands []CommandConfig) {
+	for _, cmd := range commands {
+		entity := mqttclient.MqttEntity{
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			Component:   cmd.Component,
+			Interval:    cmd.Interval,
+		}
+		if cmd.Component == "switch" {
+			// 未配置 state_command 时以最后一次执行成功的命令作为状态
+			var mu sync.Mutex
+			state := "OFF"
+			entity.ValueTemplate = "value_json.state"
+			client.RegisterSensor(entity,
+				func(_ mqtt.Client, msg mqtt.Message) {
+					target, command := "OFF", cmd.OffCommand
+					if string(msg.Payload()) == "ON" {
+						target, command = "ON", cmd.OnCommand
+					}
+					if runCommand(cmd.Name, command) == nil {
+						mu.Lock()
+						state = target
+						mu.Unlock()
+					}
+				},
+				func() interface{} {
+					if cmd.StateCommand != "" {
+						if mqttclient.RunShellCommand(cmd.StateCommand) == nil {
+							return map[string]string{"state": "ON"}
+						}
+						return map[string]string{"state": "OFF"}
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					return map[string]string{"state": state}
+				},
+			)
+			continue
+		}
+		entity.Component = "button"
+		client.RegisterSensor(entity,
+			func(_ mqtt.Client, msg mqtt.Message) {
+				runCommand(cmd.Name, cmd.Command)
+			},
+			nil,
+		)
+	}
+}
+
+func runCommand(name, command string) error {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	fmt.Println("执行命令:", name)
+	err := mqttclient.RunShellCommand(command)
+	if err != nil {
+		fmt.Println("命令执行失败:", name, err)
+	}
+	return err
+}
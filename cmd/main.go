@@ -6,50 +6,77 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	mqttclient "github.com/LanSilence/hamqtt/pkg/mqtt"
 )
 
-// CustomSensor 自定义传感器实现
+// CustomSensor 自定义传感器实现，作为采集器注册到客户端
 type CustomSensor struct {
 	currentValue float64
 }
 
-func (s *CustomSensor) GetReportData() (interface{}, error) {
-	return map[string]interface{}{
+func (s *CustomSensor) Name() string {
+	return "custom"
+}
+
+func (s *CustomSensor) Interval() time.Duration {
+	return 5 * time.Second
+}
+
+func (s *CustomSensor) Collect() (map[string]any, error) {
+	// 模拟传感器值变化
+	s.currentValue += 0.5
+	if s.currentValue > 30.0 {
+		s.currentValue = 25.0
+	}
+	return map[string]any{
 		"custom_value": s.currentValue,
 	}, nil
 }
 
-func (s *CustomSensor) GetSensorEntities() []mqttclient.SensorEntity {
-	return []mqttclient.SensorEntity{
+func (s *CustomSensor) Entities() []mqttclient.MqttEntity {
+	return []mqttclient.MqttEntity{
 		{
 			Name:              "custom_sensor",
 			Description:       "Custom Sensor Example",
+			Component:         "sensor",
 			DeviceClass:       "temperature",
 			UnitOfMeasurement: "°C",
-			ValueTemplate:     "custom_value",
+			ValueTemplate:     "value_json.custom_value",
 		},
 	}
 }
 
 func main() {
-	// 解析命令行参数
-	server := flag.String("server", "tcp://localhost", "MQTT broker address")
-	port := flag.String("port", "1883", "MQTT broker port")
-	user := flag.String("user", "haos", "MQTT username")
-	pass := flag.String("pass", "123456", "MQTT password")
-	clientID := flag.String("client-id", "hamqtt-client", "MQTT client ID")
+	// 解析命令行参数，优先级: 命令行 > 环境变量 > 配置文件 > 默认值
+	configFile := flag.String("config", os.Getenv("HAMQTT_CONFIG"), "Configuration file (YAML or JSON)")
+	flag.String("server", "tcp://localhost", "MQTT broker address")
+	flag.String("port", "1883", "MQTT broker port")
+	flag.String("user", "", "MQTT username")
+	flag.String("pass", "", "MQTT password")
+	flag.String("client-id", "hamqtt-client", "MQTT client ID")
+	flag.String("ca-file", "", "CA certificate file (PEM) for TLS")
+	flag.String("cert-file", "", "Client certificate file (PEM) for mutual TLS")
+	flag.String("key-file", "", "Client private key file (PEM) for mutual TLS")
+	flag.String("tls-server-name", "", "Override the server name used to verify the broker certificate")
+	flag.Bool("tls-insecure", false, "Skip broker certificate verification (testing only)")
+	flag.Duration("interval", mqttclient.DefaultPublishInterval, "Default state publish interval")
 	flag.Parse()
 
-	// 配置MQTT客户端
-	cfg := mqttclient.MQTTConfig{
-		Server:   *server,
-		Port:     *port,
-		User:     *user,
-		Pass:     *pass,
-		ClientID: *clientID,
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := conf.applyEnv(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
+	conf.applyFlags(flag.CommandLine)
+
+	// 配置MQTT客户端
+	cfg := conf.toMQTTConfig()
 
 	// 创建自定义传感器实例
 	customSensor := &CustomSensor{currentValue: 25.0}
@@ -62,25 +89,12 @@ func main() {
 	}
 	defer client.Stop()
 
-	// 使用RegisterSensor注册自定义传感器
-	client.RegisterSensor(
-		mqttclient.SensorEntity{
-			Name:              "custom_sensor",
-			Description:       "Custom Sensor Example",
-			DeviceClass:       "temperature",
-			UnitOfMeasurement: "°C",
-			ValueTemplate:     "custom_value",
-		},
-		nil, // 无命令处理
-		func() interface{} {
-			// 模拟传感器值变化
-			customSensor.currentValue += 0.5
-			if customSensor.currentValue > 30.0 {
-				customSensor.currentValue = 25.0
-			}
-			return customSensor.currentValue
-		},
-	)
+	// 注册自定义采集器
+	if err := client.RegisterCollector(customSensor); err != nil {
+		fmt.Printf("Failed to register collector: %v\n", err)
+	}
+	registerCommands(client, conf.Commands)
+	registerUpdate(client, conf)
 
 	fmt.Println("MQTT client started with custom sensor")
 
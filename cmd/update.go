@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/LanSilence/hamqtt/internal/selfupdate"
+	mqttclient "github.com/LanSilence/hamqtt/pkg/mqtt"
+)
+
+// version 当前版本，发布时通过 -ldflags "-X main.version=x.y.z" 设置
+var version = "1.0"
+
+// registerUpdate 注册自更新实体，从 update.source 指定的发布目录或清单文件读取最新版本
+func registerUpdate(client *mqttclient.MQTTClient, conf *Config) {
+	if conf.Update.Source == "" {
+		return
+	}
+	updater := &selfupdate.Updater{Source: conf.Update.Source, Installed: version}
+	interval := conf.Update.Interval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	entity := mqttclient.MqttEntity{
+		Name:        "hamqtt_update",
+		Description: "hamqtt update",
+		Component:   "update",
+		DeviceClass: "firmware",
+		Interval:    interval,
+	}
+	state := func() mqttclient.UpdateState {
+		s := mqttclient.UpdateState{InstalledVersion: updater.Installed, Title: "hamqtt"}
+		release, err := updater.Latest()
+		if err != nil {
+			fmt.Println("读取最新版本失败:", err)
+			s.LatestVersion = updater.Installed
+			return s
+		}
+		s.LatestVersion, s.ReleaseSummary, s.ReleaseURL = release.Version, release.Summary, release.URL
+		return s
+	}
+	err := client.RegisterUpdate(entity,
+		func(progress func(int)) error {
+			fmt.Println("开始安装更新")
+			release, err := updater.Install(progress)
+			if err != nil {
+				return err
+			}
+			fmt.Println("更新已安装，重新启动:", release.Version)
+			s := state()
+			s.InstalledVersion = release.Version
+			if err := client.PublishState(entity, s); err != nil {
+				fmt.Println("状态发布失败:", err)
+			}
+			client.Stop()
+			if err := updater.Restart(); err != nil {
+				// 连接已断开，退出后由服务管理器重新启动
+				fmt.Println("重新启动失败:", err)
+				os.Exit(1)
+			}
+			return nil
+		},
+		state,
+	)
+	if err != nil {
+		fmt.Println("注册更新实体失败:", err)
+	}
+}
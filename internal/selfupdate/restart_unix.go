@@ -0,0 +1,13 @@
+//go:build !windows
+
+package selfupdate
+
+import (
+	"os"
+	"syscall"
+)
+
+// restart 使用 exec 替换当前进程，进程ID不变，systemd 等服务管理器不会感知重启
+func restart(exe string) error {
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package selfupdate
+
+import (
+	"os"
+	"os/exec"
+)
+
+// restart Windows 不支持 exec，启动新进程后退出当前进程
+func restart(exe string) error {
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,283 @@
+package selfupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Release 一个可安装的版本
+type Release struct {
+	Version string `yaml:"version"`
+	File    string `yaml:"file"`   // 二进制文件路径，相对路径基于清单所在目录
+	SHA256  string `yaml:"sha256"` // 十六进制 SHA-256 校验和
+	Summary string `yaml:"summary"`
+	URL     string `yaml:"url"`
+}
+
+// manifest 清单文件(YAML 或 JSON)，platforms 按 <GOOS>_<GOARCH> 覆盖 file/sha256
+type manifest struct {
+	Release   `yaml:",inline"`
+	Platforms map[string]struct {
+		File   string `yaml:"file"`
+		SHA256 string `yaml:"sha256"`
+	} `yaml:"platforms"`
+}
+
+// Updater 从本地发布目录或清单文件读取最新版本并替换当前程序
+// Source 为目录时，查找 hamqtt_<version>_<GOOS>_<GOARCH> 文件及同名的 .sha256 校验文件
+type Updater struct {
+	Source     string
+	Installed  string // 当前运行的版本，安装后不会修改
+	Executable string // 被替换的程序路径，为空时使用当前程序
+}
+
+// platform 当前平台，如 linux_amd64
+func platform() string {
+	return runtime.GOOS + "_" + runtime.GOARCH
+}
+
+// Latest 读取最新的发布版本
+func (u *Updater) Latest() (*Release, error) {
+	info, err := os.Stat(u.Source)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return latestInDir(u.Source)
+	}
+	return readManifest(u.Source)
+}
+
+func readManifest(path string) (*Release, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("解析更新清单失败: %w", err)
+	}
+	if p, ok := m.Platforms[platform()]; ok {
+		m.File, m.SHA256 = p.File, p.SHA256
+	}
+	if m.Version == "" || m.File == "" || m.SHA256 == "" {
+		return nil, fmt.Errorf("更新清单缺少 version/file/sha256: %s", path)
+	}
+	if !filepath.IsAbs(m.File) {
+		m.File = filepath.Join(filepath.Dir(path), m.File)
+	}
+	return &m.Release, nil
+}
+
+func latestInDir(dir string) (*Release, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	suffix := "_" + platform()
+	if runtime.GOOS == "windows" {
+		suffix += ".exe"
+	}
+	var latest *Release
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "hamqtt_") || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "hamqtt_"), suffix)
+		if latest != nil && CompareVersions(version, latest.Version) <= 0 {
+			continue
+		}
+		sum, err := os.ReadFile(filepath.Join(dir, name+".sha256"))
+		if err != nil {
+			// 没有校验文件的版本不能安装
+			continue
+		}
+		fields := strings.Fields(string(sum))
+		if len(fields) == 0 {
+			continue
+		}
+		latest = &Release{Version: version, File: filepath.Join(dir, name), SHA256: fields[0]}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("发布目录中没有 %s 平台的版本: %s", platform(), dir)
+	}
+	return latest, nil
+}
+
+// CompareVersions 比较点分版本号，忽略前缀 v 和 +build 元数据，返回 -1, 0, 1
+// 带 -rc1 等预发布后缀的版本低于对应的正式版本，如 1.0.0-rc1 < 1.0.0
+func CompareVersions(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "+")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "+")
+	coreA, preA, hasPreA := strings.Cut(a, "-")
+	coreB, preB, hasPreB := strings.Cut(b, "-")
+	if c := compareParts(coreA, coreB); c != 0 {
+		return c
+	}
+	switch {
+	case hasPreA && hasPreB:
+		return compareParts(preA, preB)
+	case hasPreA:
+		return -1
+	case hasPreB:
+		return 1
+	}
+	return 0
+}
+
+// compareParts 逐段比较点分字符串，数字按数值比较，其余按字符串比较，缺少的段视为 0
+func compareParts(a, b string) int {
+	pa := strings.Split(a, ".")
+	pb := strings.Split(b, ".")
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		sa, sb := "0", "0"
+		if i < len(pa) {
+			sa = pa[i]
+		}
+		if i < len(pb) {
+			sb = pb[i]
+		}
+		na, errA := strconv.Atoi(sa)
+		nb, errB := strconv.Atoi(sb)
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && sa != sb:
+			return strings.Compare(sa, sb)
+		}
+	}
+	return 0
+}
+
+func (u *Updater) executable() (string, error) {
+	if u.Executable != "" {
+		return u.Executable, nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// progressWriter 按已写入的字节数报告进度
+type progressWriter struct {
+	total, written int64
+	last           int
+	progress       func(int)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.total > 0 && w.progress != nil {
+		// 复制占总进度的 90%，剩余为校验和替换
+		if percent := int(w.written * 90 / w.total); percent != w.last {
+			w.last = percent
+			w.progress(percent)
+		}
+	}
+	return len(p), nil
+}
+
+// Install 安装最新版本：复制到程序所在目录的临时文件，校验 SHA-256 后原子替换当前程序
+// 返回已安装的版本，Installed 保持不变；安装成功后需调用 Restart 运行新版本
+func (u *Updater) Install(progress func(int)) (*Release, error) {
+	release, err := u.Latest()
+	if err != nil {
+		return nil, err
+	}
+	if CompareVersions(release.Version, u.Installed) <= 0 {
+		return nil, fmt.Errorf("已是最新版本: %s", u.Installed)
+	}
+	if err := u.replace(release, progress); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// replace 用发布版本替换当前程序
+func (u *Updater) replace(release *Release, progress func(int)) error {
+	exe, err := u.executable()
+	if err != nil {
+		return fmt.Errorf("获取程序路径失败: %w", err)
+	}
+	src, err := os.Open(release.File)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	// 临时文件必须和程序在同一文件系统，才能原子重命名
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".hamqtt-update-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	writer := &progressWriter{total: info.Size(), progress: progress}
+	if _, err := io.Copy(io.MultiWriter(tmp, hash, writer), src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("复制新版本失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, release.SHA256) {
+		return fmt.Errorf("SHA-256 校验失败: 期望 %s, 实际 %s", release.SHA256, sum)
+	}
+	if progress != nil {
+		progress(95)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	old := ""
+	if runtime.GOOS == "windows" {
+		// Windows 不能覆盖正在运行的程序，但可以重命名
+		old = exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("替换程序失败: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		if old != "" {
+			os.Rename(old, exe)
+		}
+		return fmt.Errorf("替换程序失败: %w", err)
+	}
+	if progress != nil {
+		progress(100)
+	}
+	return nil
+}
+
+// Restart 以相同的参数和环境变量运行新版本程序
+func (u *Updater) Restart() error {
+	exe, err := u.executable()
+	if err != nil {
+		return err
+	}
+	return restart(exe)
+}
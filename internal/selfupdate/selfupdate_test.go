@@ -0,0 +1,27 @@
+package selfupdate
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1", 0},
+		{"v1.2.0", "1.2", 0},
+		{"1.10", "1.9", 1},
+		{"1.2.3", "1.3", -1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0", "1.0.0-rc1", 1},
+		{"1.0.0-rc2", "1.0.0-rc1", 1},
+		{"1.0.0-rc.10", "1.0.0-rc.9", 1},
+		{"1.0.1-rc1", "1.0.0", 1},
+		{"1.0.0+build5", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 type DiskInfo struct {
 	Mountpoint  string  `json:"mountpoint"`
+	Device      string  `json:"device"`
+	Fstype      string  `json:"fstype"`
 	Total       uint64  `json:"total"`
 	Free        uint64  `json:"free"`
 	Used        uint64  `json:"used"`
@@ -29,6 +31,8 @@ func GetAllDisksInfo() ([]DiskInfo, error) {
 		}
 		disks = append(disks, DiskInfo{
 			Mountpoint:  p.Mountpoint,
+			Device:      p.Device,
+			Fstype:      p.Fstype,
 			Total:       usage.Total,
 			Free:        usage.Free,
 			Used:        usage.Used,
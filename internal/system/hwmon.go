@@ -0,0 +1,177 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hwmon 传感器类型
+const (
+	HwmonTemperature = "temp" // 摄氏度
+	HwmonFan         = "fan"  // 转速 RPM
+	HwmonVoltage     = "in"   // 伏特
+)
+
+// HwmonSensor Linux hwmon 中的一个传感器输入
+type HwmonSensor struct {
+	Chip   string  `json:"chip"`   // 芯片名称，如 coretemp, k10temp, nct6775
+	Hwmon  string  `json:"hwmon"`  // 所在目录，如 hwmon0，重启后可能变化
+	Device string  `json:"device"` // 所属设备在 sysfs 中的路径，如 devices/pci0000:00/0000:00:1d.0/nvme/nvme0，没有时为空
+	Type   string  `json:"type"`   // temp, fan, in
+	Input  string  `json:"input"`  // 输入名称，如 temp1
+	Label  string  `json:"label"`  // 标签，没有 _label 文件时与 Input 相同
+	Value  float64 `json:"value"`  // 已换算为 °C, RPM, V
+}
+
+// HwmonScanner 扫描 <Root>/class/hwmon 下的全部传感器
+type HwmonScanner struct {
+	Root string // sysfs 根目录，默认 /sys
+}
+
+var hwmonInput = regexp.MustCompile(`^(temp|fan|in)(\d+)_input$`)
+
+// Scan 读取全部温度、风扇和电压输入，按芯片、所属设备和输入排序
+func (s HwmonScanner) Scan() ([]HwmonSensor, error) {
+	root := s.Root
+	if root == "" {
+		root = "/sys"
+	}
+	dirs, err := filepath.Glob(filepath.Join(root, "class", "hwmon", "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	var sensors []HwmonSensor
+	for _, dir := range dirs {
+		device := hwmonDevice(root, dir)
+		chip := readTrimmed(filepath.Join(dir, "name"))
+		// 旧内核的传感器文件位于 device 子目录
+		for _, sub := range []string{dir, filepath.Join(dir, "device")} {
+			if chip == "" {
+				chip = readTrimmed(filepath.Join(sub, "name"))
+			}
+			entries, err := os.ReadDir(sub)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				m := hwmonInput.FindStringSubmatch(e.Name())
+				if m == nil {
+					continue
+				}
+				raw, err := strconv.ParseFloat(readTrimmed(filepath.Join(sub, e.Name())), 64)
+				if err != nil {
+					continue
+				}
+				input := m[1] + m[2]
+				label := readTrimmed(filepath.Join(sub, input+"_label"))
+				if label == "" {
+					label = input
+				}
+				value := raw
+				if m[1] != HwmonFan {
+					// 温度单位为毫摄氏度，电压单位为毫伏
+					value = raw / 1000
+				}
+				sensors = append(sensors, HwmonSensor{
+					Chip:   chip,
+					Hwmon:  filepath.Base(dir),
+					Device: device,
+					Type:   m[1],
+					Input:  input,
+					Label:  label,
+					Value:  value,
+				})
+			}
+		}
+	}
+	sort.SliceStable(sensors, func(i, j int) bool {
+		if sensors[i].Chip != sensors[j].Chip {
+			return sensors[i].Chip < sensors[j].Chip
+		}
+		// hwmonN 的编号取决于驱动加载顺序，同名芯片按所属设备排序
+		if sensors[i].Device != sensors[j].Device {
+			return sensors[i].Device < sensors[j].Device
+		}
+		if sensors[i].Hwmon != sensors[j].Hwmon {
+			return sensors[i].Hwmon < sensors[j].Hwmon
+		}
+		return naturalLess(sensors[i].Input, sensors[j].Input)
+	})
+	return sensors, nil
+}
+
+// hwmonDevice hwmon 目录的 device 链接指向的设备路径(相对于 root)，重启后不变
+func hwmonDevice(root, dir string) string {
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		return ""
+	}
+	if base, err := filepath.EvalSymlinks(root); err == nil {
+		if rel, err := filepath.Rel(base, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// naturalLess 按类型和编号排序，temp2 排在 temp10 之前
+func naturalLess(a, b string) bool {
+	ta, na := splitInput(a)
+	tb, nb := splitInput(b)
+	if ta != tb {
+		return ta < tb
+	}
+	return na < nb
+}
+
+func splitInput(input string) (string, int) {
+	i := strings.IndexAny(input, "0123456789")
+	if i < 0 {
+		return input, 0
+	}
+	n, _ := strconv.Atoi(input[i:])
+	return input[:i], n
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// cpuTemperatureChips 常见的 CPU 温度芯片，按优先级排列
+var cpuTemperatureChips = []string{"coretemp", "k10temp", "zenpower", "cpu_thermal", "soc_thermal", "acpitz"}
+
+// CPUTemperature 从 hwmon 中查找 CPU 温度，优先使用封装温度(Package id 0 / Tctl)
+func (s HwmonScanner) CPUTemperature() (float64, bool) {
+	sensors, err := s.Scan()
+	if err != nil {
+		return 0, false
+	}
+	for _, chip := range cpuTemperatureChips {
+		var first *HwmonSensor
+		for i := range sensors {
+			sensor := &sensors[i]
+			if sensor.Chip != chip || sensor.Type != HwmonTemperature {
+				continue
+			}
+			label := strings.ToLower(sensor.Label)
+			if strings.HasPrefix(label, "package") || label == "tctl" || label == "tdie" {
+				return sensor.Value, true
+			}
+			if first == nil {
+				first = sensor
+			}
+		}
+		if first != nil {
+			return first.Value, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,192 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeTree 在 root 下按相对路径创建文件
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHwmonScan(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		// 带标签和没有 _label 文件的输入
+		"class/hwmon/hwmon0/name":        "nct6775",
+		"class/hwmon/hwmon0/temp1_input": "41500",
+		"class/hwmon/hwmon0/temp1_label": "SYSTIN",
+		"class/hwmon/hwmon0/fan2_input":  "1200",
+		"class/hwmon/hwmon0/in10_input":  "1800",
+		"class/hwmon/hwmon0/in2_input":   "3312",
+		"class/hwmon/hwmon0/in2_label":   "AVCC",
+		"class/hwmon/hwmon0/temp1_max":   "80000", // 不是 _input，忽略
+		// 旧内核：name 和传感器位于 device 子目录
+		"class/hwmon/hwmon1/device/name":        "w83627hf",
+		"class/hwmon/hwmon1/device/temp1_input": "35000",
+		// 同名芯片
+		"class/hwmon/hwmon2/name":        "nvme",
+		"class/hwmon/hwmon2/temp1_input": "38850",
+		"class/hwmon/hwmon2/temp1_label": "Composite",
+		"class/hwmon/hwmon3/name":        "nvme",
+		"class/hwmon/hwmon3/temp1_input": "40850",
+		"class/hwmon/hwmon3/temp1_label": "Composite",
+		// 读数无效
+		"class/hwmon/hwmon4/name":        "broken",
+		"class/hwmon/hwmon4/temp1_input": "N/A",
+	})
+
+	// 同名芯片按 device 链接指向的设备排序，与 hwmonN 编号无关
+	for hwmon, device := range map[string]string{"hwmon2": "0000:02:00.0", "hwmon3": "0000:01:00.0"} {
+		target := filepath.Join(root, "devices/pci0000:00", device, "nvme")
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, filepath.Join(root, "class/hwmon", hwmon, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sensors, err := HwmonScanner{Root: root}.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HwmonSensor{
+		{Chip: "nct6775", Hwmon: "hwmon0", Type: HwmonFan, Input: "fan2", Label: "fan2", Value: 1200},
+		{Chip: "nct6775", Hwmon: "hwmon0", Type: HwmonVoltage, Input: "in2", Label: "AVCC", Value: 3.312},
+		{Chip: "nct6775", Hwmon: "hwmon0", Type: HwmonVoltage, Input: "in10", Label: "in10", Value: 1.8},
+		{Chip: "nct6775", Hwmon: "hwmon0", Type: HwmonTemperature, Input: "temp1", Label: "SYSTIN", Value: 41.5},
+		{Chip: "nvme", Hwmon: "hwmon3", Device: "devices/pci0000:00/0000:01:00.0/nvme", Type: HwmonTemperature, Input: "temp1", Label: "Composite", Value: 40.85},
+		{Chip: "nvme", Hwmon: "hwmon2", Device: "devices/pci0000:00/0000:02:00.0/nvme", Type: HwmonTemperature, Input: "temp1", Label: "Composite", Value: 38.85},
+		{Chip: "w83627hf", Hwmon: "hwmon1", Device: "class/hwmon/hwmon1/device", Type: HwmonTemperature, Input: "temp1", Label: "temp1", Value: 35},
+	}
+	if len(sensors) != len(want) {
+		t.Fatalf("got %d sensors, want %d: %+v", len(sensors), len(want), sensors)
+	}
+	for i := range want {
+		if sensors[i] != want[i] {
+			t.Errorf("sensor %d = %+v, want %+v", i, sensors[i], want[i])
+		}
+	}
+}
+
+func TestHwmonScanMissingRoot(t *testing.T) {
+	sensors, err := HwmonScanner{Root: filepath.Join(t.TempDir(), "missing")}.Scan()
+	if err != nil || len(sensors) != 0 {
+		t.Fatalf("Scan() = %v, %v, want no sensors", sensors, err)
+	}
+}
+
+func TestHwmonCPUTemperature(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  float64
+		ok    bool
+	}{
+		{
+			name: "封装温度优先于核心温度",
+			files: map[string]string{
+				"class/hwmon/hwmon0/name":        "coretemp",
+				"class/hwmon/hwmon0/temp2_input": "45000",
+				"class/hwmon/hwmon0/temp2_label": "Core 0",
+				"class/hwmon/hwmon0/temp1_input": "52000",
+				"class/hwmon/hwmon0/temp1_label": "Package id 0",
+			},
+			want: 52,
+			ok:   true,
+		},
+		{
+			name: "Tctl",
+			files: map[string]string{
+				"class/hwmon/hwmon0/name":        "k10temp",
+				"class/hwmon/hwmon0/temp3_input": "39000",
+				"class/hwmon/hwmon0/temp3_label": "Tccd1",
+				"class/hwmon/hwmon0/temp1_input": "48000",
+				"class/hwmon/hwmon0/temp1_label": "Tctl",
+			},
+			want: 48,
+			ok:   true,
+		},
+		{
+			name: "芯片优先级高于扫描顺序",
+			files: map[string]string{
+				"class/hwmon/hwmon0/name":        "acpitz",
+				"class/hwmon/hwmon0/temp1_input": "27800",
+				"class/hwmon/hwmon1/name":        "coretemp",
+				"class/hwmon/hwmon1/temp2_input": "44000",
+				"class/hwmon/hwmon1/temp2_label": "Core 0",
+			},
+			want: 44,
+			ok:   true,
+		},
+		{
+			name: "没有封装温度时使用第一个输入",
+			files: map[string]string{
+				"class/hwmon/hwmon0/name":        "acpitz",
+				"class/hwmon/hwmon0/temp2_input": "30000",
+				"class/hwmon/hwmon0/temp1_input": "27800",
+			},
+			want: 27.8,
+			ok:   true,
+		},
+		{
+			name: "没有 CPU 芯片",
+			files: map[string]string{
+				"class/hwmon/hwmon0/name":        "nvme",
+				"class/hwmon/hwmon0/temp1_input": "38850",
+			},
+			ok: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got, ok := HwmonScanner{Root: root}.CPUTemperature()
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("CPUTemperature() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGetCPUTemperatureRoot(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("仅支持 Linux")
+	}
+	root := t.TempDir()
+	if _, err := GetCPUTemperature(root); err != ErrTemperatureNotFound {
+		t.Fatalf("空目录: err = %v, want ErrTemperatureNotFound", err)
+	}
+	// 没有 CPU thermal_zone 时回退到 hwmon
+	writeTree(t, root, map[string]string{
+		"class/thermal/thermal_zone0/type": "acpitz",
+		"class/thermal/thermal_zone0/temp": "27800",
+		"class/hwmon/hwmon0/name":          "coretemp",
+		"class/hwmon/hwmon0/temp1_input":   "52000",
+		"class/hwmon/hwmon0/temp1_label":   "Package id 0",
+	})
+	if got, err := GetCPUTemperature(root); err != nil || got != 52 {
+		t.Fatalf("hwmon: GetCPUTemperature() = %v, %v, want 52", got, err)
+	}
+	writeTree(t, root, map[string]string{
+		"class/thermal/thermal_zone1/type": "x86_pkg_temp",
+		"class/thermal/thermal_zone1/temp": "55000",
+	})
+	if got, err := GetCPUTemperature(root); err != nil || got != 55 {
+		t.Fatalf("thermal_zone: GetCPUTemperature() = %v, %v, want 55", got, err)
+	}
+}
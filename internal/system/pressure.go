@@ -0,0 +1,65 @@
+package system
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PressureResources Linux PSI 支持的资源
+var PressureResources = []string{"cpu", "memory", "io"}
+
+// Pressure 一行 PSI 数据，some 表示至少一个任务等待，full 表示全部任务等待
+type Pressure struct {
+	Resource string  `json:"resource"` // cpu, memory, io
+	Kind     string  `json:"kind"`     // some, full
+	Avg10    float64 `json:"avg10"`    // 最近10秒等待时间占比 %
+	Avg60    float64 `json:"avg60"`
+	Avg300   float64 `json:"avg300"`
+	Total    uint64  `json:"total"` // 累计等待微秒数
+}
+
+// ReadPressure 读取 <procRoot>/pressure 下的全部 PSI 数据，procRoot 默认 /proc
+// 内核未开启 PSI 时返回空
+func ReadPressure(procRoot string) []Pressure {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	var pressures []Pressure
+	for _, resource := range PressureResources {
+		f, err := os.Open(filepath.Join(procRoot, "pressure", resource))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			p := Pressure{Resource: resource, Kind: fields[0]}
+			for _, field := range fields[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					continue
+				}
+				switch key {
+				case "avg10":
+					p.Avg10, _ = strconv.ParseFloat(value, 64)
+				case "avg60":
+					p.Avg60, _ = strconv.ParseFloat(value, 64)
+				case "avg300":
+					p.Avg300, _ = strconv.ParseFloat(value, 64)
+				case "total":
+					p.Total, _ = strconv.ParseUint(value, 10, 64)
+				}
+			}
+			pressures = append(pressures, p)
+		}
+		f.Close()
+	}
+	return pressures
+}
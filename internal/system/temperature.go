@@ -1,11 +1,13 @@
 package system
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/LanSilence/hamqtt/pkg"
 )
@@ -15,8 +17,14 @@ type Win32_TemperatureProbe struct {
 	Description        string
 }
 
-func getCPUTemperatureLinux() (float32, error) {
-	files, err := filepath.Glob("/sys/class/thermal/thermal_zone*/temp")
+// cpuThermalZones 表示 CPU 温度的 thermal_zone 类型
+var cpuThermalZones = []string{"cpu", "x86_pkg_temp", "soc"}
+
+func getCPUTemperatureLinux(root string) (float32, error) {
+	if root == "" {
+		root = "/sys"
+	}
+	files, err := filepath.Glob(filepath.Join(root, "class", "thermal", "thermal_zone*", "temp"))
 	if err != nil {
 		return 0, err
 	}
@@ -35,32 +43,53 @@ func getCPUTemperatureLinux() (float32, error) {
 		}
 
 		// 检查是否是CPU温度(可选)
-		typeData, err := os.ReadFile(strings.Replace(file, "temp", "type", 1))
-		if err == nil && strings.Contains(strings.ToLower(string(typeData)), "cpu") {
-			return float32(temp) / 1000, nil
+		typeData, err := os.ReadFile(filepath.Join(filepath.Dir(file), "type"))
+		if err != nil {
+			continue
+		}
+		zone := strings.ToLower(string(typeData))
+		for _, name := range cpuThermalZones {
+			if strings.Contains(zone, name) {
+				return float32(temp) / 1000, nil
+			}
 		}
 	}
 
-	return 0, fmt.Errorf("CPU temperature not found")
+	// x86 主机通常只能通过 hwmon(coretemp/k10temp/acpitz) 获取
+	if temp, ok := (HwmonScanner{Root: root}).CPUTemperature(); ok {
+		return float32(temp), nil
+	}
+	return 0, ErrTemperatureNotFound
 }
 
+// ErrTemperatureNotFound 当前系统无法获取 CPU 温度
+var ErrTemperatureNotFound = errors.New("CPU temperature not found")
+
+// GetCPUTemperature 获取 CPU 温度，root 为 sysfs 根目录，默认 /sys
+func GetCPUTemperature(root string) (float64, error) {
+	if pkg.GetOSType() != "linux" {
+		return 0, ErrTemperatureNotFound
+	}
+	temp, err := getCPUTemperatureLinux(root)
+	if err != nil {
+		return 0, err
+	}
+	return float64(temp), nil
+}
+
+// temperatureErrorLogged 找不到温度时只记录一次日志
+var temperatureErrorLogged atomic.Bool
+
+// GetDeviceTemperature 获取 CPU 温度，获取失败时返回 -0.001
+//
+// Deprecated: 使用 GetCPUTemperature，失败时不应发布温度
 func GetDeviceTemperature() float64 {
-	// 这里可以添加获取设备温度的逻辑
-	if pkg.GetOSType() == "windows" {
-		// temp, err := getCPUTemperatureWindows()
-		// if err != nil {
-		// 	fmt.Println("Error getting CPU temperature:", err)
-		// 	return -0.001 // 返回默认值
-		// }
-		return -0.001
-	} else if pkg.GetOSType() == "linux" {
-		temp, err := getCPUTemperatureLinux()
-		if err != nil {
+	temp, err := GetCPUTemperature("")
+	if err != nil {
+		if !temperatureErrorLogged.Swap(true) {
 			fmt.Println("Error getting CPU temperature:", err)
-			return -0.001 // 返回默认值
 		}
-		return float64(temp)
-
+		return -0.001
 	}
-	return 25.0
+	return temp
 }
@@ -0,0 +1,82 @@
+package mqtt
+
+import (
+	"fmt"
+	"log"
+)
+
+// 常用的 binary_sensor 设备类型
+const (
+	BinaryDeviceClassProblem      = "problem"
+	BinaryDeviceClassConnectivity = "connectivity"
+	BinaryDeviceClassPlug         = "plug"
+	BinaryDeviceClassRunning      = "running"
+	BinaryDeviceClassUpdate       = "update"
+	BinaryDeviceClassSafety       = "safety"
+)
+
+var validBinaryDeviceClasses = map[string]bool{
+	"battery": true, "battery_charging": true, "carbon_monoxide": true, "cold": true,
+	"connectivity": true, "door": true, "garage_door": true, "gas": true, "heat": true,
+	"light": true, "lock": true, "moisture": true, "motion": true, "moving": true,
+	"occupancy": true, "opening": true, "plug": true, "power": true, "presence": true,
+	"problem": true, "running": true, "safety": true, "smoke": true, "sound": true,
+	"tamper": true, "update": true, "vibration": true, "window": true,
+}
+
+// BinarySensorOptions binary_sensor 的可选配置，通过 MqttEntity.ExternalOptions 传入
+type BinarySensorOptions struct {
+	PayloadOn  string //（可选）：表示开启的负载，默认 ON
+	PayloadOff string //（可选）：表示关闭的负载，默认 OFF
+	OffDelay   int    //（可选）：收到开启状态后自动变为关闭的秒数
+}
+
+func (o *BinarySensorOptions) payloads() (string, string) {
+	on, off := "ON", "OFF"
+	if o != nil && o.PayloadOn != "" {
+		on = o.PayloadOn
+	}
+	if o != nil && o.PayloadOff != "" {
+		off = o.PayloadOff
+	}
+	return on, off
+}
+
+// applyBinarySensorOptions 写入 binary_sensor 的发现配置
+func applyBinarySensorOptions(payload map[string]any, entity MqttEntity) {
+	options, _ := entity.ExternalOptions.(*BinarySensorOptions)
+	payload["payload_on"], payload["payload_off"] = options.payloads()
+	if options != nil && options.OffDelay > 0 {
+		payload["off_delay"] = options.OffDelay
+	}
+	if entity.DeviceClass != "" && !validBinaryDeviceClasses[entity.DeviceClass] {
+		log.Println("无效的 binary_sensor 设备类型:", entity.DeviceClass)
+		delete(payload, "device_class")
+	}
+}
+
+// RegisterBinarySensor 注册一个 binary_sensor 实体
+// stateHandler - 返回当前布尔状态，true 发布 payload_on，false 发布 payload_off
+func (c *MQTTClient) RegisterBinarySensor(entity MqttEntity, stateHandler func() bool) error {
+	if stateHandler == nil {
+		return fmt.Errorf("binary_sensor 需要状态回调: %s", entity.Name)
+	}
+	if err := c.ensureDevice(entity.Device); err != nil {
+		return err
+	}
+	entity.Component = "binary_sensor"
+	if entity.ValueTemplate == "" {
+		entity.ValueTemplate = "value_json.state"
+	}
+	options, _ := entity.ExternalOptions.(*BinarySensorOptions)
+	on, off := options.payloads()
+	c.registerSensor(entity, nil, func() interface{} {
+		if stateHandler() {
+			return map[string]string{"state": on}
+		}
+		return map[string]string{"state": off}
+	}, func(entity MqttEntity) error {
+		return c.RegisterBinarySensor(entity, stateHandler)
+	})
+	return nil
+}
@@ -1,57 +1,104 @@
 package mqtt
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"os"
+	"sync"
 	"time"
 
 	"os/exec"
 
 	"maps"
 
-	"github.com/LanSilence/hamqtt/internal/system"
 	"github.com/LanSilence/hamqtt/pkg"
 	"github.com/denisbrodbeck/machineid"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
-	"github.com/shirou/gopsutil/cpu"
-	"github.com/shirou/gopsutil/disk"
-	"github.com/shirou/gopsutil/mem"
 )
 
-var unique_id int = 0
+// 可用性主题的负载，LWT 发布 offline，每次连接成功后发布 online
+const (
+	PayloadAvailable    = "online"
+	PayloadNotAvailable = "offline"
+)
 
+// LightOptions JSON schema 灯光的选项，亮度、颜色和效果都通过灯光的命令/状态主题以 JSON 收发
 type LightOptions struct {
-	SupportsBrightness        bool
+	SupportsBrightness bool
+	Brightness_scale   int //（可选）：亮度最大值，默认255
+
+	// Deprecated: JSON schema 灯光不使用单独的亮度主题和模板，以下字段被忽略
 	Brightness_command_topic  string
-	Brightness_scale          int
 	Brightness_state_topic    string
 	Brightness_value_template string
 
-	SupportsRGB          bool
-	Rgb_command_template string //（可选）：定义用于发送给RGB状态的消息的模板。可用变量：red、green和blue。
-	Rgb_command_topic    string //（可选）：用于发布更改灯光RGB状态的MQTT主题。
-	Rgb_state_topic      string //（可选）：订阅以接收RGB状态更新的MQTT主题。
-	Rgb_value_template   string //（可选）：定义提取RGB值的模板。
-
-	SupportsEffects       bool
-	Effect_command_topic  string   //（可选）：用于发布更改灯光效果的MQTT主题。
-	EffectList            []string //（可选）：支持的效果列表。
-	Effect_state_topic    string   //（可选）：订阅以接收灯光效果状态更新的MQTT主题。
-	Effect_value_template string   //（可选）：定义提取灯光效果状态的模板。
+	SupportsRGB bool
+
+	// Deprecated: JSON schema 灯光不使用单独的RGB主题和模板，以下字段被忽略
+	Rgb_command_template string
+	Rgb_command_topic    string
+	Rgb_state_topic      string
+	Rgb_value_template   string
+
+	SupportsColorTemp bool
+	ColorTempKelvin   bool // 为 true 时色温以开尔文收发，否则使用 mireds
+	MinMireds         int  //（可选）：最小色温(mireds)
+	MaxMireds         int  //（可选）：最大色温(mireds)
+	MinKelvin         int  //（可选）：最小色温(K)
+	MaxKelvin         int  //（可选）：最大色温(K)
+
+	// 支持的颜色模式: onoff, brightness, color_temp, hs, xy, rgb, rgbw, rgbww, white
+	// 为空时根据 SupportsBrightness/SupportsRGB/SupportsColorTemp 推导
+	SupportedColorModes []string
+	White_scale         int //（可选）：white 通道最大值，默认255
+
+	SupportsFlash    bool
+	Flash_time_short int //（可选）：短闪烁时长(秒)
+	Flash_time_long  int //（可选）：长闪烁时长(秒)
+
+	SupportsEffects bool
+	EffectList      []string //（可选）：支持的效果列表，效果通过 LightCommand.Effect / LightState.Effect 收发
+
+	// Deprecated: JSON schema 灯光不使用单独的效果主题和模板，以下字段被忽略
+	Effect_command_topic  string
+	Effect_state_topic    string
+	Effect_value_template string
 }
 
 // MqttEntity 定义传感器实体
 type MqttEntity struct {
 	Name              string
-	Component         string // 组件类型: sensor, switch, light, button 等
+	Component         string // 组件类型: sensor(默认), binary_sensor, switch, light, button, number, select, text, update 等
 	Description       string
 	DeviceClass       string         // 设备显示的图标类型
 	UnitOfMeasurement string         // 单位
 	ValueTemplate     string         // 状态值模板 value_json.xxx
-	OtherConfig       map[string]any // 外部选项
-	ExternalOptions   interface{}    // 外部选项
+	Interval          time.Duration  // 状态发布间隔，为0时使用 MQTTConfig.PublishInterval
+	Policy            *PublishPolicy // 发布策略(变化/死区/心跳)，为 nil 时每个周期都发布
+	Device            *Device        // 所属设备，为 nil 时属于主设备
+
+	// 通用元数据，不适用于该组件类型的字段会被忽略
+	Icon                      string        // 图标，如 mdi:harddisk
+	EntityCategory            string        // diagnostic 或 config
+	StateClass                string        // 仅 sensor: measurement, total, total_increasing
+	SuggestedDisplayPrecision *int          // 仅 sensor: 建议显示的小数位数
+	ExpireAfter               time.Duration // 仅 sensor/binary_sensor: 超过该时间未更新则显示为不可用
+	EnabledByDefault          *bool         // 为 false 时实体默认禁用
+	ForceUpdate               bool          // 仅 sensor/binary_sensor: 值未变化也触发状态更新
+	JSONAttributesTopic       string        // 属性主题，设置了 Attributes 时默认为实体的 attributes 主题
+	JSONAttributesTemplate    string        // 属性模板；采集器实体未设置属性主题时从采集器的状态中提取
+
+	Attributes func() map[string]any // 返回 JSON 属性的可选回调，按 Interval 发布到属性主题
+
+	OtherConfig     map[string]any // 直接合并到自动发现配置的额外字段，可覆盖自动生成的字段
+	ExternalOptions interface{}    // 外部选项
+}
+
+// component 实体的组件类型，未设置时为 sensor
+func (e MqttEntity) component() string {
+	if e.Component == "" {
+		return "sensor"
+	}
+	return e.Component
 }
 
 type SystemInfo struct {
@@ -77,56 +124,135 @@ type MQTTConfig struct {
 	User     string `json:"user"`
 	Pass     string `json:"pass"`
 	ClientID string `json:"client_id"`
+
+	// TLS 配置，Server 使用 ssl://、mqtts://、wss:// 等加密协议时启用，设置了以下任一项时 Server 必须使用加密协议
+	CAFile             string `json:"ca_file"`              // 私有CA证书(PEM)
+	CertFile           string `json:"cert_file"`            // 客户端证书(PEM)，用于双向认证
+	KeyFile            string `json:"key_file"`             // 客户端私钥(PEM)
+	ServerName         string `json:"server_name"`          // 覆盖证书校验使用的服务器名
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // 跳过服务器证书校验，仅用于测试
+
+	DisabledCollectors []string      `json:"disabled_collectors"` // 不注册的内置采集器: cpu, memory, memory_stats, swap, disk, disks, diskio, hwmon, network, pressure, system, temperature
+	DiskFilter         DiskFilter    `json:"disk_filter"`         // disks 采集器的挂载点和文件系统筛选
+	HwmonRoot          string        `json:"hwmon_root"`          // hwmon 和 temperature 采集器使用的 sysfs 根目录，默认 /sys
+	DiskIOFilter       DiskIOFilter  `json:"diskio_filter"`       // diskio 采集器的块设备筛选
+	NetworkFilter      NetworkFilter `json:"network_filter"`      // network 采集器的接口筛选
+
+	PublishInterval    time.Duration             `json:"publish_interval"`    // 默认发布间隔，为0时使用 DefaultPublishInterval
+	CollectorIntervals map[string]time.Duration  `json:"collector_intervals"` // 按采集器名称覆盖发布间隔
+	PublishPolicies    map[string]*PublishPolicy `json:"publish_policies"`    // 按实体名称设置发布策略，实体自身未设置时生效
+
+	Device       Device `json:"device"`        // 设备显示信息，为空的字段使用默认值
+	PowerAction  string `json:"power_action"`  // 电源开关关闭时的动作: suspend(默认), shutdown, reboot, command, none
+	PowerCommand string `json:"power_command"` // PowerAction 为 command 时执行的命令
+
+	// 主题布局，见 topicBuilder
+	DiscoveryPrefix string `json:"discovery_prefix"` // 自动发现前缀，默认 homeassistant
+	BaseTopic       string `json:"base_topic"`       // 状态/命令/可用性主题的前缀，如 hamqtt，为空时位于自动发现前缀下
+	NodeID          string `json:"node_id"`          // 节点ID模板，支持 {hostname} {client_id}，默认 {hostname}{client_id}，Device.ID 非空时不生效
+
+	BirthTopic       string        `json:"birth_topic"`        // HomeAssistant 出生主题，默认 <discovery_prefix>/status
+	MaxAnnounceDelay time.Duration `json:"max_announce_delay"` // 收到 HA 上线消息后重新发布前的最大随机延迟，默认5秒
+
+	// 自动发现配置的发布方式: entity(默认) 或 device
+	// 切换到 device 时会迁移并清除旧的按实体发布的配置
+	DiscoveryMode string `json:"discovery_mode"`
+	Version       string `json:"version"` // hamqtt 的版本，发布到自动发现配置的 origin 中，为空时省略
+
+	TopicHandlers map[string]mqtt.MessageHandler `json:"-"` // 额外订阅的主题及回调，每次连接成功后订阅
 }
 
 type MQTTClient struct {
 	client          mqtt.Client
 	deviceName      string
 	deviceID        string
+	device          Device                  // 主设备信息
+	devices         map[string]*deviceState // 桥接模式下的子设备
 	publishStopChan chan struct{}
-	sensors         []MqttEntity // 直接注册的传感器
+	mu              sync.Mutex
+	handlers        map[string]mqtt.MessageHandler // 主题-回调映射，每次连接成功后重新订阅
+	defaultEntities []MqttEntity                   // 内置的非采集器实体
+	sensors         []*registeredEntity            // 直接注册的传感器
+	collectors      collectorRegistry
+	scheduler       *scheduler              // 所有周期发布任务共用的调度器
+	gates           map[string]*publishGate // 任务键 -> 设置了发布策略的状态发布判定
+
+	topics            topicBuilder // 全部主题由此生成
+	maxAnnounceDelay  time.Duration
+	discoveryMode     string
+	version           string                       // origin 中的 hamqtt 版本
+	legacyTopics      map[string]bool              // 设备模式下代理上仍保留的旧的按实体配置主题，待迁移
+	settling          bool                         // 设备模式下正在等待代理推送旧配置，推迟发布设备配置
+	migrateMu         sync.Mutex                   // 串行发布设备配置和迁移
+	removedComponents map[string]map[string]string // 设备模式下已注销的实体: 节点 -> unique_id -> 组件类型
+	powerAction       string
+	powerCommand      string
+
+	publishInterval    time.Duration
+	collectorIntervals map[string]time.Duration
+	publishPolicies    map[string]*PublishPolicy
 }
 
-var internalHandlers *map[string]mqtt.MessageHandler // 内部主题-回调映射
-// 设置订阅主题及回调（仅保存，需重建 client 后生效）
-func MqttSetTopicHandlers(topicHandlers map[string]mqtt.MessageHandler) {
-	if internalHandlers == nil {
-		internalHandlers = &map[string]mqtt.MessageHandler{}
+// MqttSetTopicHandlers 设置订阅主题及回调，已连接时立即订阅，并在每次重连后自动重新订阅
+func (c *MQTTClient) MqttSetTopicHandlers(topicHandlers map[string]mqtt.MessageHandler) {
+	c.mu.Lock()
+	if c.handlers == nil {
+		c.handlers = map[string]mqtt.MessageHandler{}
 	}
-	for k, v := range topicHandlers {
-		(*internalHandlers)[k] = v
+	maps.Copy(c.handlers, topicHandlers)
+	c.mu.Unlock()
+	if c.client == nil || !c.client.IsConnected() {
+		return
+	}
+	for topic, handler := range topicHandlers {
+		token := c.client.Subscribe(topic, 1, handler)
+		token.Wait()
+		if token.Error() != nil {
+			fmt.Println("订阅失败:", topic, token.Error())
+		}
 	}
 }
 
-func handlePowerMessage(client mqtt.Client, msg mqtt.Message) {
+// 电源开关收到 OFF 时执行的动作
+const (
+	PowerActionSuspend  = "suspend"
+	PowerActionShutdown = "shutdown"
+	PowerActionReboot   = "reboot"
+	PowerActionCommand  = "command" // 执行 MQTTConfig.PowerCommand
+	PowerActionNone     = "none"
+)
+
+func (c *MQTTClient) handlePowerMessage(client mqtt.Client, msg mqtt.Message) {
 	if !client.IsConnected() {
 		return
 	}
 	if string(msg.Payload()) == "OFF" {
-		fmt.Println("收到关机指令，准备休眠...")
+		fmt.Println("收到关机指令，执行电源动作:", c.powerAction)
 		go func() {
-			err := crossPlatformSuspend()
+			err := runPowerAction(c.powerAction, c.powerCommand)
 			if err != nil {
-				fmt.Println("休眠失败:", err)
+				fmt.Println("电源动作执行失败:", err)
 			} else {
-				fmt.Println("休眠成功")
+				fmt.Println("电源动作执行成功")
 			}
 		}()
 	}
 }
 
-func getUniqueId() int {
-	unique_id++
-	return unique_id
-}
-
-var deviceName string = "unknown"
-var deviceID string = "0000"
-
-func initDevInfo(cfg MQTTConfig) {
-	deviceName, _ = os.Hostname()
-	deviceID, _ = machineid.ID()
-	deviceID = cfg.ClientID
+// initDevInfo 初始化设备名称和ID，用于生成主题和 unique_id
+func (c *MQTTClient) initDevInfo(cfg MQTTConfig) {
+	c.deviceName, _ = os.Hostname()
+	if c.deviceName == "" {
+		c.deviceName = "unknown"
+	}
+	c.deviceID = cfg.ClientID
+	if c.deviceID == "" {
+		c.deviceID, _ = machineid.ID()
+	}
+	c.device = cfg.Device.withDefaults(c.deviceName)
+	if c.device.ID == "" {
+		c.device.ID = expandNodeID(cfg.NodeID, c.deviceName, c.deviceID)
+	}
 }
 
 /*
@@ -146,107 +272,131 @@ func initDevInfo(cfg MQTTConfig) {
 	    }
 	}
 */
-func getPayload(entity MqttEntity) map[string]any {
+func (c *MQTTClient) getPayload(entity MqttEntity) map[string]any {
 
-	uniqueID := deviceID + "_" + entity.Name
+	node := c.nodeID(entity.Device)
+	uniqueID := c.deviceID + "_" + entity.Name
+	if entity.Device != nil {
+		uniqueID = node + "_" + entity.Name
+	}
 	payload := map[string]any{
-		"name":           entity.Name,
-		"device_class":   entity.DeviceClass,
-		"state_topic":    "homeassistant/" + entity.Component + "/" + deviceName + deviceID + "/state",
-		"unique_id":      uniqueID,
-		"value_template": "{{ " + entity.ValueTemplate + " }}",
-		"device": map[string]any{
-			"identifiers":  []string{deviceName + deviceID},
-			"name":         deviceName,
-			"manufacturer": "HaPerfMonitor",
-			"model":        "MQTT Monitor",
-			"sw_version":   "1.0",
-		},
+		"name":              entity.Name,
+		"state_topic":       c.topics.state(entity.component(), node),
+		"unique_id":         uniqueID,
+		"value_template":    "{{ " + entity.ValueTemplate + " }}",
+		"availability":      c.availabilityPayload(entity.Device),
+		"availability_mode": "all",
+		"device":            c.devicePayload(entity.Device),
 	}
-
-	if entity.ExternalOptions != nil {
-		// 循环遍历 entity.ExternalOptions中的内容
-		maps.Copy(payload, entity.OtherConfig)
+	// HomeAssistant 不接受空的 device_class
+	if entity.DeviceClass != "" {
+		payload["device_class"] = entity.DeviceClass
 	}
 
-	if entity.Component == "switch" {
-		payload["command_topic"] = "homeassistant/switch/" + deviceName + deviceID + "/" + entity.Name + "/set"
+	if entity.component() == "switch" {
+		payload["command_topic"] = c.topics.entity("switch", node, entity.Name, "set")
+		payload["state_topic"] = c.topics.entity("switch", node, entity.Name, "state")
 		payload["payload_on"] = "ON"
 		payload["payload_off"] = "OFF"
-	} else if entity.Component == "light" {
-		var options *LightOptions = entity.ExternalOptions.(*LightOptions)
-		payload["command_topic"] = "homeassistant/light/" + deviceName + deviceID + "/" + entity.Name + "/set"
-		payload["state_topic"] = "homeassistant/light/" + deviceName + deviceID + "/" + entity.Name + "/state"
+	} else if entity.component() == "light" {
+		options, _ := entity.ExternalOptions.(*LightOptions)
+		payload["command_topic"] = c.topics.entity("light", node, entity.Name, "set")
+		payload["state_topic"] = c.topics.entity("light", node, entity.Name, "state")
 		payload["schema"] = "json"
-		if options != nil {
-			if options.SupportsBrightness {
-				log.Println("亮度控制功能暂未支持")
-				// payload["brightness"] = true
-				// payload["brightness_scale"] = 255
-			}
-
-			if options.SupportsRGB { //暂未支持
-				log.Println("RGB功能暂未支持")
-				// payload["rgb"] = true
-			}
-
-			if options.SupportsEffects {
-				payload["effect"] = true
-				if len(options.EffectList) > 0 {
-					payload["effect_list"] = options.EffectList
-				}
-				if options.Effect_command_topic != "" {
-					payload["effect_command_topic"] = options.Effect_command_topic
-				}
-				if options.Effect_state_topic != "" {
-					payload["effect_state_topic"] = options.Effect_state_topic
-				}
-				if options.Effect_value_template != "" {
-					payload["effect_value_template"] = options.Effect_value_template
-				}
-			}
+		// JSON schema 灯光的状态由整条 JSON 描述，不使用 value_template/device_class
+		delete(payload, "value_template")
+		delete(payload, "device_class")
+		applyLightOptions(payload, options)
+	} else if entity.component() == "binary_sensor" {
+		payload["state_topic"] = c.topics.entity("binary_sensor", node, entity.Name, "state")
+		applyBinarySensorOptions(payload, entity)
+	} else if entity.component() == "number" || entity.component() == "select" || entity.component() == "text" {
+		payload["command_topic"] = c.topics.entity(entity.component(), node, entity.Name, "set")
+		payload["state_topic"] = c.topics.entity(entity.component(), node, entity.Name, "state")
+		applyControlOptions(payload, entity)
+		if entity.component() == "number" && entity.UnitOfMeasurement != "" {
+			payload["unit_of_measurement"] = entity.UnitOfMeasurement
 		}
-	} else if entity.Component == "button" {
-		payload["command_topic"] = "homeassistant/button/" + deviceName + deviceID + "/" + entity.Name + "/set"
+	} else if entity.component() == "update" {
+		payload["command_topic"] = c.topics.entity("update", node, entity.Name, "set")
+		payload["state_topic"] = c.topics.entity("update", node, entity.Name, "state")
+		payload["payload_install"] = PayloadInstall
+		// 状态为完整的 UpdateState JSON
+		delete(payload, "value_template")
+	} else if entity.component() == "button" {
+		payload["command_topic"] = c.topics.entity("button", node, entity.Name, "set")
 	} else if entity.UnitOfMeasurement != "" {
 		payload["unit_of_measurement"] = entity.UnitOfMeasurement
 	}
+	if entity.Attributes != nil && entity.JSONAttributesTopic == "" {
+		entity.JSONAttributesTopic = c.attributesTopic(entity)
+	}
+	applyMetadata(payload, entity)
+	maps.Copy(payload, entity.OtherConfig)
 
 	return payload
 
 }
 
-func getTopic(component string, sensorName string) string {
+// getAvailabilityTopic 设备的可用性主题，所有实体共用
+func (c *MQTTClient) getAvailabilityTopic() string {
+	return c.deviceAvailabilityTopic(nil)
+}
+
+func (c *MQTTClient) getTopic(entity MqttEntity) string {
 	// 根据HomeAssistant MQTT自动发现规范构建主题
-	// 主题格式: homeassistant/<component>/[<node_id>/]<object_id>/config
-	return "homeassistant/" + component + "/" + deviceName + deviceID + "/" + sensorName + "/config"
+	// 主题格式: <discovery_prefix>/<component>/[<node_id>/]<object_id>/config
+	return c.topics.config(entity.component(), c.nodeID(entity.Device), entity.Name)
+}
+
+// entityKey 实体在客户端内的唯一标识
+func (c *MQTTClient) entityKey(entity MqttEntity) string {
+	return c.nodeID(entity.Device) + "/" + entity.Name
 }
 
 func NewMQTTClient(cfg MQTTConfig) (*MQTTClient, error) {
-	initDevInfo(cfg)
 	client := &MQTTClient{
-		deviceName: deviceName,
-		deviceID:   cfg.ClientID,
+		handlers:           map[string]mqtt.MessageHandler{},
+		devices:            map[string]*deviceState{},
+		gates:              map[string]*publishGate{},
+		scheduler:          newScheduler(),
+		publishInterval:    cfg.PublishInterval,
+		collectorIntervals: cfg.CollectorIntervals,
+		publishPolicies:    cfg.PublishPolicies,
+		maxAnnounceDelay:   cfg.MaxAnnounceDelay,
+		discoveryMode:      cfg.DiscoveryMode,
+		version:            cfg.Version,
+		removedComponents:  map[string]map[string]string{},
+		legacyTopics:       map[string]bool{},
+		powerAction:        cfg.PowerAction,
+		powerCommand:       cfg.PowerCommand,
+		topics:             newTopicBuilder(cfg.DiscoveryPrefix, cfg.BaseTopic),
+	}
+	client.initDevInfo(cfg)
+	maps.Copy(client.handlers, cfg.TopicHandlers)
+	if client.powerAction == "" {
+		client.powerAction = PowerActionSuspend
+	}
+	if client.publishInterval <= 0 {
+		client.publishInterval = DefaultPublishInterval
+	}
+	if client.maxAnnounceDelay <= 0 {
+		client.maxAnnounceDelay = DefaultMaxAnnounceDelay
+	}
+	switch client.discoveryMode {
+	case "":
+		client.discoveryMode = DiscoveryModeEntity
+	case DiscoveryModeEntity, DiscoveryModeDevice:
+	default:
+		return nil, fmt.Errorf("不支持的自动发现方式: %s", cfg.DiscoveryMode)
+	}
+	birthTopic := cfg.BirthTopic
+	if birthTopic == "" {
+		birthTopic = client.topics.birth()
 	}
 
-	// 注册默认实体
-	defaultEntities := []MqttEntity{
-		{
-			Name:              "memory",
-			Description:       "Memory Usage",
-			Component:         "sensor",
-			DeviceClass:       "humidity",
-			UnitOfMeasurement: "%",
-			ValueTemplate:     "value_json.mem_usage",
-		},
-		{
-			Name:              "cpu",
-			Description:       "CPU Usage",
-			Component:         "sensor",
-			DeviceClass:       "humidity",
-			UnitOfMeasurement: "%",
-			ValueTemplate:     "value_json.cpu_usage",
-		},
+	// 注册默认实体，监控指标由内置采集器提供
+	client.defaultEntities = []MqttEntity{
 		{
 			Name:              "power",
 			Description:       "Device Power",
@@ -255,14 +405,9 @@ func NewMQTTClient(cfg MQTTConfig) (*MQTTClient, error) {
 			UnitOfMeasurement: "",
 			ValueTemplate:     "value_json.power_status",
 		},
-		{
-			Name:              "temperature",
-			Description:       "Device Temperature",
-			Component:         "sensor",
-			DeviceClass:       "temperature",
-			UnitOfMeasurement: "°C",
-			ValueTemplate:     "value_json.temperature",
-		},
+	}
+	for _, col := range builtinCollectors(cfg) {
+		client.collectors.add(col)
 	}
 
 	broker := cfg.Server + ":" + cfg.Port
@@ -271,58 +416,45 @@ func NewMQTTClient(cfg MQTTConfig) (*MQTTClient, error) {
 	opts.SetClientID(cfg.ClientID)
 	opts.SetUsername(cfg.User)
 	opts.SetPassword(cfg.Pass)
-	// 设置LWT和可用性主题
-	opts.SetWill("homeassistant/switch/"+deviceName+deviceID+"/state", `{"power_status":"OFF"}`, 1, false)
-	// 注册自动订阅
-	if internalHandlers == nil {
-		internalHandlers = &map[string]mqtt.MessageHandler{"homeassistant/switch/" + deviceName + deviceID + "/power/set": handlePowerMessage}
+	secure, err := cfg.useTLS()
+	if err != nil {
+		return nil, err
+	}
+	if secure {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
 	}
-	if internalHandlers != nil {
-		setOnConnectSubscribe(opts)
+	// 设置LWT和可用性主题，异常断开时由代理发布 offline
+	opts.SetWill(client.getAvailabilityTopic(), PayloadNotAvailable, 1, true)
+	// 每次(重新)连接成功后发布在线状态，重新订阅并发布全部实体配置和状态
+	opts.SetOnConnectHandler(client.onConnect)
+	client.handlers[client.topics.entity("switch", client.device.ID, "power", "set")] = client.handlePowerMessage
+	// HomeAssistant 重启后重新发布自动发现配置
+	client.handlers[birthTopic] = client.handleBirthMessage
+	if client.discoveryMode == DiscoveryModeDevice {
+		// 查找旧的按实体配置，只迁移代理上仍保留的配置
+		client.handlers[client.topics.legacyConfigs(client.device.ID)] = client.handleLegacyConfig
 	}
 	client.client = mqtt.NewClient(opts)
 	if token := client.client.Connect(); token.WaitTimeout(time.Second*5) && token.Error() != nil {
 		return nil, token.Error()
 	}
-	// 发布在线状态
-	client.client.Publish("homeassistant/sensor/"+deviceName+deviceID+"/status", 1, true, "online")
-
-	// 注册自定义实体
-
-	// 发布默认实体配置
-	for _, entity := range defaultEntities {
-		topic := getTopic(entity.Component, entity.Name)
-		payload := getPayload(entity)
-		jsonData, _ := json.MarshalIndent(payload, "", "  ")
-		token := client.client.Publish(topic, 1, true, string(jsonData))
-		token.Wait()
+	for _, col := range client.collectors.all() {
+		client.scheduleCollector(col)
 	}
+	client.schedule("power", client.publishInterval, client.publishPowerState)
 	client.publishStopChan = make(chan struct{})
 	// 订阅set主题，收到OFF时休眠
 
+	go client.scheduler.run(client.publishStopChan)
 	go client.publishServerStatus()
 	return client, nil
 }
 
-// 新的订阅注册方式，需在创建 client 前设置 OnConnect
-func setOnConnectSubscribe(opts *mqtt.ClientOptions) {
-	oldHandler := opts.OnConnect
-	opts.OnConnect = func(c mqtt.Client) {
-		if oldHandler != nil {
-			oldHandler(c)
-		}
-		for topic, handler := range *internalHandlers {
-			token := c.Subscribe(topic, 1, handler)
-			token.Wait()
-			if token.Error() != nil {
-				fmt.Println("订阅失败:", topic, token.Error())
-			} else {
-				fmt.Println("已订阅主题:", topic)
-			}
-		}
-	}
-}
-
+// publishServerStatus 维护连接，断开时重连；周期发布由调度器负责
 func (c *MQTTClient) publishServerStatus() {
 	retryCount := 0
 	retryInterval := 5 * time.Second
@@ -359,143 +491,61 @@ func (c *MQTTClient) publishServerStatus() {
 				}
 			}
 		}
-		var cpuSum float64
-		samples := 2
-		for i := 0; i < samples; i++ {
-			percentages, err := cpu.Percent(time.Millisecond*500, false)
-			if err == nil && len(percentages) > 0 {
-				cpuSum += percentages[0]
-			}
-		}
-		cpuAvg := cpuSum / float64(samples)
-		// 仅在 Windows 下修正 cpuAvg
-		if pkg.GetOSType() == "windows" && cpuAvg < 10 {
-			cpuAvg = cpuAvg * 10
-		}
-		mem, _ := mem.VirtualMemory()
-		memPercent := float64(mem.Used) / float64(mem.Total) * 100
-		disks, _ := disk.Usage("/")
-		diskPercent := float64(disks.Used) / float64(disks.Total) * 100
-		temperature := system.GetDeviceTemperature()
-		// 创建基础状态信息
-		info := map[string]interface{}{
-			"cpu_usage":   cpuAvg,
-			"mem_usage":   memPercent,
-			"disk_usage":  diskPercent,
-			"temperature": temperature,
-		}
-
-		stateTopic := "homeassistant/sensor/" + c.deviceName + c.deviceID + "/state"
-		payload, err := json.Marshal(info)
-		if err != nil {
-			panic(err)
-		}
-		token := c.client.Publish(stateTopic, 1, true, payload)
-		token.Wait()
+		time.Sleep(time.Second)
+	}
+}
 
-		stateTopic = "homeassistant/switch/" + c.deviceName + c.deviceID + "/state"
-		payload = []byte(`{"power_status":"ON"}`)
-		if err != nil {
-			panic(err)
-		}
+func (c *MQTTClient) publishPowerState() {
+	stateTopic := c.topics.entity("switch", c.device.ID, "power", "state")
+	token := c.client.Publish(stateTopic, 1, true, []byte(`{"power_status":"ON"}`))
+	token.Wait()
+}
 
-		token = c.client.Publish(stateTopic, 1, true, payload)
-		token.Wait()
-		time.Sleep(2 * time.Second)
+// schedule 向共用调度器添加周期任务，断开连接期间跳过执行
+func (c *MQTTClient) schedule(key string, interval time.Duration, run func()) {
+	if interval <= 0 {
+		interval = c.publishInterval
 	}
+	c.scheduler.add(key, interval, func() {
+		if c.client != nil && c.client.IsConnected() {
+			run()
+		}
+	})
 }
 
-// RegisterSensor 直接注册一个传感器实体
+// RegisterSensor 直接注册一个传感器实体，同名(同设备)实体已存在时替换
 // commandHandler - 处理命令消息的可选回调
 // stateHandler - 返回当前状态值的可选回调
 func (c *MQTTClient) RegisterSensor(entity MqttEntity,
 	commandHandler mqtt.MessageHandler,
 	stateHandler func() interface{}) {
 
-	c.sensors = append(c.sensors, entity)
-	topic := getTopic(entity.DeviceClass, entity.Name)
-	payload := getPayload(entity)
-	jsonData, _ := json.MarshalIndent(payload, "", "  ")
-	if c.client != nil && c.client.IsConnected() {
-		c.client.Publish(topic, 1, true, string(jsonData))
-
-		// 注册命令处理handler
-		if commandHandler != nil {
-			MqttSetTopicHandlers(map[string]mqtt.MessageHandler{
-				"homeassistant/" + entity.DeviceClass + "/" + c.deviceName + c.deviceID + "/" + entity.Name + "/set": commandHandler,
-			})
-			if c.client.IsConnected() {
-				token := c.client.Subscribe(
-					payload["command_topic"].(string),
-					1,
-					commandHandler,
-				)
-				token.Wait()
-			}
-
-			if payload["effect_command_topic"] != nil {
-				MqttSetTopicHandlers(map[string]mqtt.MessageHandler{
-					payload["effect_command_topic"].(string): commandHandler,
-				})
-				if c.client.IsConnected() {
-					token := c.client.Subscribe(
-						payload["effect_command_topic"].(string),
-						1,
-						commandHandler,
-					)
-					token.Wait()
-				}
-			}
-		}
+	c.registerSensor(entity, commandHandler, stateHandler, nil)
+}
 
-		// 注册状态更新处理
-		if stateHandler != nil {
-			topic := payload["state_topic"].(string)
-			go func() {
-				ticker := time.NewTicker(2 * time.Second)
-				defer ticker.Stop()
-				for {
-					select {
-					case <-ticker.C:
-						state := stateHandler()
-						payload, _ := json.Marshal(state)
-						c.client.Publish(
-							topic,
-							1,
-							true,
-							payload,
-						)
-					case <-c.publishStopChan:
-						return
-					}
-				}
-			}()
-
-			if payload["effect_state_topic"] != nil {
-				topic := payload["effect_state_topic"].(string)
-				go func() {
-					ticker := time.NewTicker(2 * time.Second)
-					defer ticker.Stop()
-					for {
-						select {
-						case <-ticker.C:
-							state := stateHandler()
-							payload, _ := json.Marshal(state)
-							c.client.Publish(
-								topic,
-								1,
-								true,
-								payload,
-							)
-						case <-c.publishStopChan:
-							return
-						}
-					}
-				}()
+// registerSensor 注册实体，reregister 为类型化注册函数在 UpdateEntity 时重新注册的回调
+func (c *MQTTClient) registerSensor(entity MqttEntity,
+	commandHandler mqtt.MessageHandler,
+	stateHandler func() interface{},
+	reregister func(MqttEntity) error) {
 
-			}
-		}
+	if err := c.ensureDevice(entity.Device); err != nil {
+		fmt.Println("注册设备失败:", entity.Name, err)
+		return
 	}
+	reg := &registeredEntity{
+		entity:         entity,
+		commandHandler: commandHandler,
+		stateHandler:   stateHandler,
+		reregister:     reregister,
+	}
+	c.mu.Lock()
+	old := c.replaceSensor(reg)
+	c.mu.Unlock()
+	if old != nil {
+		c.detachEntity(old)
+	}
+	c.attachEntity(reg)
 }
 
 func (c *MQTTClient) Stop() {
@@ -504,6 +554,9 @@ func (c *MQTTClient) Stop() {
 		c.publishStopChan = nil
 	}
 	if c.client != nil && c.client.IsConnected() {
+		// 正常退出不会触发 LWT，需主动发布离线状态
+		token := c.client.Publish(c.getAvailabilityTopic(), 1, true, PayloadNotAvailable)
+		token.WaitTimeout(time.Second)
 		c.client.Disconnect(250)
 	}
 }
@@ -522,3 +575,61 @@ func crossPlatformSuspend() error {
 		return fmt.Errorf("不支持的操作系统: %s", osType)
 	}
 }
+
+// 跨平台关机辅助函数
+func crossPlatformShutdown() error {
+	osType := pkg.GetOSType()
+	switch osType {
+	case "windows":
+		return exec.Command("shutdown", "/s", "/t", "0").Run()
+	case "linux":
+		return exec.Command("systemctl", "poweroff").Run()
+	case "darwin":
+		return exec.Command("shutdown", "-h", "now").Run()
+	default:
+		return fmt.Errorf("不支持的操作系统: %s", osType)
+	}
+}
+
+// 跨平台重启辅助函数
+func crossPlatformReboot() error {
+	osType := pkg.GetOSType()
+	switch osType {
+	case "windows":
+		return exec.Command("shutdown", "/r", "/t", "0").Run()
+	case "linux":
+		return exec.Command("systemctl", "reboot").Run()
+	case "darwin":
+		return exec.Command("shutdown", "-r", "now").Run()
+	default:
+		return fmt.Errorf("不支持的操作系统: %s", osType)
+	}
+}
+
+// RunShellCommand 使用系统 shell 执行命令
+func RunShellCommand(command string) error {
+	if pkg.GetOSType() == "windows" {
+		return exec.Command("cmd", "/C", command).Run()
+	}
+	return exec.Command("sh", "-c", command).Run()
+}
+
+func runPowerAction(action string, command string) error {
+	switch action {
+	case PowerActionSuspend:
+		return crossPlatformSuspend()
+	case PowerActionShutdown:
+		return crossPlatformShutdown()
+	case PowerActionReboot:
+		return crossPlatformReboot()
+	case PowerActionCommand:
+		if command == "" {
+			return fmt.Errorf("未配置电源命令")
+		}
+		return RunShellCommand(command)
+	case PowerActionNone:
+		return nil
+	default:
+		return fmt.Errorf("不支持的电源动作: %s", action)
+	}
+}
@@ -0,0 +1,526 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LanSilence/hamqtt/internal/system"
+	"github.com/LanSilence/hamqtt/pkg"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/mem"
+)
+
+// Collector 指标采集器
+// Entities 返回的实体通过 ValueTemplate 引用 Collect 返回的字段，
+// 例如 Collect 返回 {"cpu_usage": 12.5}，实体模板为 value_json.cpu_usage
+type Collector interface {
+	Name() string                     // 采集器名称，同一客户端内唯一，用于状态主题和启用/禁用
+	Entities() []MqttEntity           // 需要发布自动发现配置的实体
+	Collect() (map[string]any, error) // 采集一次数据
+	Interval() time.Duration          // 采集间隔，<=0 时使用客户端的默认发布间隔
+}
+
+// errNoData 本次没有可发布的数据，跳过发布且不记录日志
+var errNoData = errors.New("no data")
+
+type funcCollector struct {
+	name     string
+	entities []MqttEntity
+	interval time.Duration
+	collect  func() (map[string]any, error)
+}
+
+func (f *funcCollector) Name() string                     { return f.name }
+func (f *funcCollector) Entities() []MqttEntity           { return f.entities }
+func (f *funcCollector) Collect() (map[string]any, error) { return f.collect() }
+func (f *funcCollector) Interval() time.Duration          { return f.interval }
+
+// NewCollector 通过采集函数构造一个 Collector
+func NewCollector(name string, interval time.Duration, entities []MqttEntity,
+	collect func() (map[string]any, error)) Collector {
+	return &funcCollector{
+		name:     name,
+		entities: entities,
+		interval: interval,
+		collect:  collect,
+	}
+}
+
+// collectorEntry 注册表中的采集器及其运行状态
+type collectorEntry struct {
+	collector Collector
+	enabled   bool
+}
+
+// collectorRegistry 保存客户端的全部采集器，按注册顺序执行
+type collectorRegistry struct {
+	mu      sync.Mutex
+	entries []*collectorEntry
+}
+
+func (r *collectorRegistry) add(col Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name() == col.Name() {
+			return fmt.Errorf("采集器已存在: %s", col.Name())
+		}
+	}
+	r.entries = append(r.entries, &collectorEntry{collector: col, enabled: true})
+	return nil
+}
+
+func (r *collectorRegistry) setEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name() == name {
+			e.enabled = enabled
+			return nil
+		}
+	}
+	return fmt.Errorf("采集器不存在: %s", name)
+}
+
+func (r *collectorRegistry) isEnabled(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.collector.Name() == name {
+			return e.enabled
+		}
+	}
+	return false
+}
+
+func (r *collectorRegistry) all() []Collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cols := make([]Collector, 0, len(r.entries))
+	for _, e := range r.entries {
+		cols = append(cols, e.collector)
+	}
+	return cols
+}
+
+func (r *collectorRegistry) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.entries))
+	for _, e := range r.entries {
+		names = append(names, e.collector.Name())
+	}
+	return names
+}
+
+// collectorStateTopic 采集器的状态主题，位于其实体所属设备下
+func (c *MQTTClient) collectorStateTopic(col Collector) string {
+	var dev *Device
+	if entities := col.Entities(); len(entities) > 0 {
+		dev = entities[0].Device
+	}
+	return c.topics.entity("sensor", c.nodeID(dev), col.Name(), "state")
+}
+
+// RegisterCollector 注册采集器并发布其实体的自动发现配置
+func (c *MQTTClient) RegisterCollector(col Collector) error {
+	for _, entity := range col.Entities() {
+		if err := c.ensureDevice(entity.Device); err != nil {
+			return err
+		}
+	}
+	if err := c.collectors.add(col); err != nil {
+		return err
+	}
+	if c.client != nil && c.client.IsConnected() {
+		c.publishCollectorConfig(col)
+	}
+	c.scheduleCollector(col)
+	return nil
+}
+
+// collectorInterval 采集器的发布间隔：配置覆盖 > 采集器自身 > 客户端默认
+func (c *MQTTClient) collectorInterval(col Collector) time.Duration {
+	if interval, ok := c.collectorIntervals[col.Name()]; ok && interval > 0 {
+		return interval
+	}
+	if interval := col.Interval(); interval > 0 {
+		return interval
+	}
+	return c.publishInterval
+}
+
+func (c *MQTTClient) scheduleCollector(col Collector) {
+	key := "collector/" + col.Name()
+	interval := c.collectorInterval(col)
+	gate := c.newGate(key, col.Entities(), false)
+	enabled := func() bool { return c.collectors.isEnabled(col.Name()) }
+	known := c.entitiesByKey(col.Entities())
+	c.schedule(key, interval, func() {
+		if !enabled() {
+			return
+		}
+		data, err := col.Collect()
+		if errors.Is(err, errNoData) {
+			return
+		}
+		if err != nil {
+			fmt.Println("采集失败:", col.Name(), err)
+			return
+		}
+		// 实体列表可能随采集结果变化(如挂载新磁盘)
+		entities := col.Entities()
+		if current := c.entitiesByKey(entities); c.syncCollectorEntities(col, known, current, entities, interval, enabled) {
+			known = current
+			gate = c.newGate(key, entities, false)
+		}
+		c.publishCollectorState(col, gate, data)
+	})
+	for _, entity := range col.Entities() {
+		c.scheduleAttributes(entity, interval, enabled)
+	}
+}
+
+// EnableCollector 恢复采集器的周期发布
+func (c *MQTTClient) EnableCollector(name string) error {
+	return c.collectors.setEnabled(name, true)
+}
+
+// DisableCollector 暂停采集器的周期发布，已发布的自动发现配置保持不变
+func (c *MQTTClient) DisableCollector(name string) error {
+	return c.collectors.setEnabled(name, false)
+}
+
+// Collectors 返回已注册的采集器名称
+func (c *MQTTClient) Collectors() []string {
+	return c.collectors.names()
+}
+
+func (c *MQTTClient) publishCollectorConfig(col Collector) {
+	c.publishConfigs(c.collectorConfigs(col))
+}
+
+// collectorConfigs 采集器实体的自动发现配置
+func (c *MQTTClient) collectorConfigs(col Collector) []entityConfig {
+	return c.collectorEntityConfigs(col, col.Entities())
+}
+
+// collectorEntityConfigs 采集器中指定实体的自动发现配置，状态主题为采集器的共用主题
+// 只设置了属性模板的实体从采集器的状态中提取属性
+func (c *MQTTClient) collectorEntityConfigs(col Collector, entities []MqttEntity) []entityConfig {
+	stateTopic := c.collectorStateTopic(col)
+	var configs []entityConfig
+	for _, entity := range entities {
+		payload := c.getPayload(entity)
+		payload["state_topic"] = stateTopic
+		if _, ok := payload["json_attributes_topic"]; !ok && entity.JSONAttributesTemplate != "" {
+			payload["json_attributes_topic"] = stateTopic
+		}
+		configs = append(configs, entityConfig{entity, payload})
+	}
+	return configs
+}
+
+// entitiesByKey 按 entityKey 索引实体
+func (c *MQTTClient) entitiesByKey(entities []MqttEntity) map[string]MqttEntity {
+	byKey := make(map[string]MqttEntity, len(entities))
+	for _, entity := range entities {
+		byKey[c.entityKey(entity)] = entity
+	}
+	return byKey
+}
+
+// syncCollectorEntities 发布新增实体的自动发现配置并删除已移除的实体，同时增删其属性发布任务
+// 只为新增实体生成配置，返回实体是否有变化
+func (c *MQTTClient) syncCollectorEntities(col Collector, known, current map[string]MqttEntity,
+	entities []MqttEntity, interval time.Duration, enabled func() bool) bool {
+	var added []MqttEntity
+	for _, entity := range entities {
+		if _, ok := known[c.entityKey(entity)]; !ok {
+			fmt.Println("新增实体:", entity.Name)
+			added = append(added, entity)
+		}
+	}
+	var removed []MqttEntity
+	for key, entity := range known {
+		if _, ok := current[key]; !ok {
+			fmt.Println("移除实体:", entity.Name)
+			removed = append(removed, entity)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return false
+	}
+	if len(added) > 0 {
+		c.publishConfigs(c.collectorEntityConfigs(col, added))
+	}
+	for _, entity := range removed {
+		c.scheduler.remove(c.attributesJobKey(entity))
+		c.deleteConfig(entity)
+	}
+	for _, entity := range added {
+		c.scheduleAttributes(entity, interval, enabled)
+	}
+	return true
+}
+
+// publishCollectorState 按发布策略决定是否发布采集结果
+func (c *MQTTClient) publishCollectorState(col Collector, gate *publishGate, data map[string]any) {
+	if !gate.allow(data, time.Now()) {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		fmt.Println("采集数据序列化失败:", col.Name(), err)
+		return
+	}
+	token := c.client.Publish(c.collectorStateTopic(col), 1, true, payload)
+	token.Wait()
+}
+
+// 内置采集器名称
+const (
+	CollectorCPU         = "cpu"
+	CollectorMemory      = "memory"
+	CollectorMemoryStats = "memory_stats" // 内存明细，与 memory 分开以便单独设置发布策略
+	CollectorSwap        = "swap"
+	CollectorDisk        = "disk" // / 的使用率，与 disks 的 disk_root_used 重复，为兼容已有实体保留
+	CollectorTemperature = "temperature"
+)
+
+// builtinCollectors 返回未被禁用的内置采集器，禁用的采集器不会被创建
+func builtinCollectors(cfg MQTTConfig) []Collector {
+	disabled := map[string]bool{}
+	for _, name := range cfg.DisabledCollectors {
+		disabled[name] = true
+	}
+	builders := []struct {
+		name  string
+		build func() Collector
+	}{
+		{CollectorCPU, func() Collector {
+			return NewCollector(CollectorCPU, 0, []MqttEntity{
+				{
+					Name:              "cpu",
+					Description:       "CPU Usage",
+					Component:         "sensor",
+					DeviceClass:       "humidity",
+					UnitOfMeasurement: "%",
+					ValueTemplate:     "value_json.cpu_usage",
+					StateClass:        StateClassMeasurement,
+					Icon:              "mdi:cpu-64-bit",
+
+					JSONAttributesTemplate: "{{ value_json.cpu_cores | tojson }}",
+				},
+			}, collectCPU)
+		}},
+		{CollectorMemory, func() Collector {
+			return NewCollector(CollectorMemory, 0, []MqttEntity{
+				{
+					Name:              "memory",
+					Description:       "Memory Usage",
+					Component:         "sensor",
+					DeviceClass:       "humidity",
+					UnitOfMeasurement: "%",
+					ValueTemplate:     "value_json.mem_usage",
+					StateClass:        StateClassMeasurement,
+					Icon:              "mdi:memory",
+				},
+			}, collectMemory)
+		}},
+		{CollectorMemoryStats, func() Collector {
+			return NewCollector(CollectorMemoryStats, 0, []MqttEntity{
+				memoryBytesEntity("memory_available", "Memory Available", "mem_available"),
+				memoryBytesEntity("memory_cached", "Memory Cached", "mem_cached"),
+				memoryBytesEntity("memory_buffers", "Memory Buffers", "mem_buffers"),
+			}, collectMemoryStats)
+		}},
+		{CollectorSwap, func() Collector {
+			return NewCollector(CollectorSwap, 0, []MqttEntity{
+				{
+					Name:              "swap",
+					Description:       "Swap Usage",
+					Component:         "sensor",
+					UnitOfMeasurement: "%",
+					ValueTemplate:     "value_json.swap_usage",
+					StateClass:        StateClassMeasurement,
+					Icon:              "mdi:swap-horizontal",
+				},
+				memoryBytesEntity("swap_used", "Swap Used", "swap_used"),
+				memoryBytesEntity("swap_total", "Swap Total", "swap_total"),
+			}, collectSwap)
+		}},
+		{CollectorDisk, func() Collector {
+			return NewCollector(CollectorDisk, 0, []MqttEntity{
+				{
+					Name:              "disk",
+					Description:       "Disk Usage",
+					Component:         "sensor",
+					UnitOfMeasurement: "%",
+					ValueTemplate:     "value_json.disk_usage",
+					StateClass:        StateClassMeasurement,
+					Icon:              "mdi:harddisk",
+
+					JSONAttributesTemplate: "{{ value_json.disk | tojson }}",
+				},
+			}, collectDisk)
+		}},
+		{CollectorTemperature, func() Collector {
+			return NewCollector(CollectorTemperature, 0, []MqttEntity{
+				{
+					Name:              "temperature",
+					Description:       "Device Temperature",
+					Component:         "sensor",
+					DeviceClass:       "temperature",
+					UnitOfMeasurement: "°C",
+					ValueTemplate:     "value_json.temperature",
+					StateClass:        StateClassMeasurement,
+				},
+			}, temperatureCollect(cfg.HwmonRoot))
+		}},
+		{CollectorDisks, func() Collector { return primeCollector(newDiskCollector(cfg.DiskFilter)) }},
+		{CollectorHwmon, func() Collector { return primeCollector(newHwmonCollector(cfg.HwmonRoot)) }},
+		{CollectorDiskIO, func() Collector { return primeCollector(newDiskIOCollector(cfg.DiskIOFilter)) }},
+		{CollectorNetwork, func() Collector { return primeCollector(newNetworkCollector(cfg.NetworkFilter)) }},
+		{CollectorSystem, func() Collector { return primeCollector(newSystemCollector()) }},
+		{CollectorPressure, func() Collector { return primeCollector(newPressureCollector()) }},
+	}
+	var cols []Collector
+	for _, b := range builders {
+		if !disabled[b.name] {
+			cols = append(cols, b.build())
+		}
+	}
+	return cols
+}
+
+// primeCollector 启动时采集一次，实体随采集结果变化的采集器在首次发布自动发现配置时即包含全部实体
+func primeCollector(col Collector) Collector {
+	if _, err := col.Collect(); err != nil {
+		fmt.Println("采集失败:", col.Name(), err)
+	}
+	return col
+}
+
+// round1 保留一位小数
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func collectCPU() (map[string]any, error) {
+	var cpuSum float64
+	var coreSums []float64
+	samples := 2
+	for i := 0; i < samples; i++ {
+		percentages, err := cpu.Percent(time.Millisecond*500, true)
+		if err != nil || len(percentages) == 0 {
+			continue
+		}
+		if coreSums == nil {
+			coreSums = make([]float64, len(percentages))
+		}
+		var total float64
+		for core, p := range percentages {
+			if core < len(coreSums) {
+				coreSums[core] += p
+			}
+			total += p
+		}
+		cpuSum += total / float64(len(percentages))
+	}
+	cpuAvg := cpuSum / float64(samples)
+	// 仅在 Windows 下修正 cpuAvg
+	if pkg.GetOSType() == "windows" && cpuAvg < 10 {
+		cpuAvg = cpuAvg * 10
+	}
+	// 每个核心的使用率作为实体属性
+	cores := make(map[string]float64, len(coreSums))
+	for core, sum := range coreSums {
+		cores[fmt.Sprintf("cpu%d", core)] = round1(sum / float64(samples))
+	}
+	return map[string]any{"cpu_usage": cpuAvg, "cpu_cores": cores}, nil
+}
+
+// memoryBytesEntity 以字节为单位的内存传感器
+func memoryBytesEntity(name, description, key string) MqttEntity {
+	return MqttEntity{
+		Name:              name,
+		Description:       description,
+		Component:         "sensor",
+		DeviceClass:       "data_size",
+		UnitOfMeasurement: "B",
+		ValueTemplate:     "value_json." + key,
+		StateClass:        StateClassMeasurement,
+		OtherConfig:       map[string]any{"suggested_unit_of_measurement": "MiB"},
+	}
+}
+
+func collectMemory() (map[string]any, error) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"mem_usage": float64(vm.Used) / float64(vm.Total) * 100}, nil
+}
+
+func collectMemoryStats() (map[string]any, error) {
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"mem_available": vm.Available,
+		"mem_cached":    vm.Cached,
+		"mem_buffers":   vm.Buffers,
+	}, nil
+}
+
+func collectSwap() (map[string]any, error) {
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"swap_usage": swap.UsedPercent,
+		"swap_used":  swap.Used,
+		"swap_total": swap.Total,
+	}, nil
+}
+
+func collectDisk() (map[string]any, error) {
+	usage, err := disk.Usage("/")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"disk_usage": float64(usage.Used) / float64(usage.Total) * 100,
+		"disk": map[string]any{
+			"mountpoint": usage.Path,
+			"fstype":     usage.Fstype,
+			"total":      usage.Total,
+			"free":       usage.Free,
+			"used":       usage.Used,
+		},
+	}, nil
+}
+
+// temperatureCollect 获取不到温度时不发布，只在首次失败时记录日志
+func temperatureCollect(root string) func() (map[string]any, error) {
+	var logged atomic.Bool
+	return func() (map[string]any, error) {
+		temp, err := system.GetCPUTemperature(root)
+		if err != nil {
+			if !logged.Swap(true) {
+				fmt.Println("获取CPU温度失败:", err)
+			}
+			return nil, errNoData
+		}
+		return map[string]any{"temperature": temp}, nil
+	}
+}
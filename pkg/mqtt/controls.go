@@ -0,0 +1,218 @@
+package mqtt
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// NumberOptions number 实体的可选配置，通过 MqttEntity.ExternalOptions 传入
+type NumberOptions struct {
+	Min  float64 //（可选）：最小值，Min 和 Max 均为0时使用 HomeAssistant 默认值(1~100)
+	Max  float64 //（可选）：最大值
+	Step float64 //（可选）：步长，默认1
+	Mode string  //（可选）：显示方式 auto, box, slider
+}
+
+// SelectOptions select 实体的配置，通过 MqttEntity.ExternalOptions 传入
+type SelectOptions struct {
+	Options []string // 可选项列表，必填
+}
+
+// TextOptions text 实体的可选配置，通过 MqttEntity.ExternalOptions 传入
+type TextOptions struct {
+	Min     int    //（可选）：最小长度
+	Max     int    //（可选）：最大长度，HomeAssistant 默认255
+	Pattern string //（可选）：校验用正则表达式
+	Mode    string //（可选）：text 或 password
+}
+
+// applyControlOptions 写入 number/select/text 的发现配置
+func applyControlOptions(payload map[string]any, entity MqttEntity) {
+	switch options := entity.ExternalOptions.(type) {
+	case *NumberOptions:
+		if options.Min != 0 || options.Max != 0 {
+			payload["min"] = options.Min
+			payload["max"] = options.Max
+		}
+		if options.Step > 0 {
+			payload["step"] = options.Step
+		}
+		if options.Mode != "" {
+			payload["mode"] = options.Mode
+		}
+	case *SelectOptions:
+		payload["options"] = options.Options
+	case *TextOptions:
+		if options.Min > 0 {
+			payload["min"] = options.Min
+		}
+		if options.Max > 0 {
+			payload["max"] = options.Max
+		}
+		if options.Pattern != "" {
+			payload["pattern"] = options.Pattern
+		}
+		if options.Mode != "" {
+			payload["mode"] = options.Mode
+		}
+	}
+	if entity.component() != "number" {
+		delete(payload, "device_class")
+	}
+}
+
+// controlValue number/select/text 的状态负载，对应模板 value_json.value
+func controlValue(v any) map[string]any {
+	return map[string]any{"value": v}
+}
+
+// registerControl 注册带类型化命令回调的实体，命令执行成功后立即回写状态
+// parse 将命令负载转换为回调参数，返回需要回写的状态值
+// reregister 为 UpdateEntity 时以新配置重新注册的回调
+func (c *MQTTClient) registerControl(entity MqttEntity,
+	parse func(payload string) (any, error),
+	apply func(value any) error,
+	stateHandler func() any,
+	reregister func(MqttEntity) error) {
+
+	if entity.ValueTemplate == "" {
+		entity.ValueTemplate = "value_json.value"
+	}
+	commandHandler := func(client mqtt.Client, msg mqtt.Message) {
+		value, err := parse(string(msg.Payload()))
+		if err != nil {
+			fmt.Println("无效的命令:", entity.Name, err)
+			return
+		}
+		if err := apply(value); err != nil {
+			fmt.Println("命令执行失败:", entity.Name, err)
+			return
+		}
+		if stateHandler != nil {
+			value = stateHandler()
+		}
+		if err := c.PublishState(entity, controlValue(value)); err != nil {
+			fmt.Println("状态发布失败:", entity.Name, err)
+		}
+	}
+	var stateFn func() interface{}
+	if stateHandler != nil {
+		stateFn = func() interface{} { return controlValue(stateHandler()) }
+	}
+	c.registerSensor(entity, commandHandler, stateFn, reregister)
+}
+
+// RegisterNumber 注册 number 实体
+// onSet - 收到新值时调用，值已按 NumberOptions 校验范围
+// stateHandler - 返回当前值的可选回调，为 nil 时以收到的值回写状态
+func (c *MQTTClient) RegisterNumber(entity MqttEntity, onSet func(float64) error, stateHandler func() float64) error {
+	if onSet == nil {
+		return fmt.Errorf("number 需要命令回调: %s", entity.Name)
+	}
+	if err := c.ensureDevice(entity.Device); err != nil {
+		return err
+	}
+	entity.Component = "number"
+	options, _ := entity.ExternalOptions.(*NumberOptions)
+	var stateFn func() any
+	if stateHandler != nil {
+		stateFn = func() any { return stateHandler() }
+	}
+	c.registerControl(entity,
+		func(payload string) (any, error) {
+			v, err := strconv.ParseFloat(strings.TrimSpace(payload), 64)
+			if err != nil {
+				return nil, err
+			}
+			if options != nil && (options.Min != 0 || options.Max != 0) && (v < options.Min || v > options.Max) {
+				return nil, fmt.Errorf("超出范围 [%v, %v]: %v", options.Min, options.Max, v)
+			}
+			return v, nil
+		},
+		func(value any) error { return onSet(value.(float64)) },
+		stateFn,
+		func(entity MqttEntity) error { return c.RegisterNumber(entity, onSet, stateHandler) },
+	)
+	return nil
+}
+
+// RegisterSelect 注册 select 实体，可选项由 SelectOptions 指定
+// onSelect - 选中某一项时调用
+// stateHandler - 返回当前选项的可选回调，为 nil 时以选中的值回写状态
+func (c *MQTTClient) RegisterSelect(entity MqttEntity, onSelect func(string) error, stateHandler func() string) error {
+	options, _ := entity.ExternalOptions.(*SelectOptions)
+	if options == nil || len(options.Options) == 0 {
+		return fmt.Errorf("select 需要 SelectOptions.Options: %s", entity.Name)
+	}
+	if onSelect == nil {
+		return fmt.Errorf("select 需要命令回调: %s", entity.Name)
+	}
+	if err := c.ensureDevice(entity.Device); err != nil {
+		return err
+	}
+	entity.Component = "select"
+	var stateFn func() any
+	if stateHandler != nil {
+		stateFn = func() any { return stateHandler() }
+	}
+	c.registerControl(entity,
+		func(payload string) (any, error) {
+			if !slices.Contains(options.Options, payload) {
+				return nil, fmt.Errorf("不在可选项中: %q", payload)
+			}
+			return payload, nil
+		},
+		func(value any) error { return onSelect(value.(string)) },
+		stateFn,
+		func(entity MqttEntity) error { return c.RegisterSelect(entity, onSelect, stateHandler) },
+	)
+	return nil
+}
+
+// RegisterText 注册 text 实体
+// onSet - 收到新文本时调用，文本已按 TextOptions 校验长度和格式
+// stateHandler - 返回当前文本的可选回调，为 nil 时以收到的文本回写状态
+func (c *MQTTClient) RegisterText(entity MqttEntity, onSet func(string) error, stateHandler func() string) error {
+	if onSet == nil {
+		return fmt.Errorf("text 需要命令回调: %s", entity.Name)
+	}
+	if err := c.ensureDevice(entity.Device); err != nil {
+		return err
+	}
+	entity.Component = "text"
+	options, _ := entity.ExternalOptions.(*TextOptions)
+	var pattern *regexp.Regexp
+	if options != nil && options.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(options.Pattern); err != nil {
+			return fmt.Errorf("无效的 text 格式: %w", err)
+		}
+	}
+	var stateFn func() any
+	if stateHandler != nil {
+		stateFn = func() any { return stateHandler() }
+	}
+	c.registerControl(entity,
+		func(payload string) (any, error) {
+			if options != nil {
+				length := len([]rune(payload))
+				if length < options.Min || (options.Max > 0 && length > options.Max) {
+					return nil, fmt.Errorf("长度超出范围: %d", length)
+				}
+			}
+			if pattern != nil && !pattern.MatchString(payload) {
+				return nil, fmt.Errorf("不匹配格式 %s: %q", options.Pattern, payload)
+			}
+			return payload, nil
+		},
+		func(value any) error { return onSet(value.(string)) },
+		stateFn,
+		func(entity MqttEntity) error { return c.RegisterText(entity, onSet, stateHandler) },
+	)
+	return nil
+}
@@ -0,0 +1,193 @@
+package mqtt
+
+import (
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Device HomeAssistant 设备信息
+// 实体通过 MqttEntity.Device 关联设备，为 nil 时属于客户端所在主机(主设备)。
+// 桥接模式下同一个 MQTTClient 可以通过多个 Device 发布多台设备，
+// 每台设备使用独立的主题和可用性主题，并通过 via_device 关联到主设备
+type Device struct {
+	ID           string   `json:"id"`          // 设备唯一ID，用于主题和 unique_id，同一客户端内唯一，只能包含 [a-zA-Z0-9_-]
+	Identifiers  []string `json:"identifiers"` // HomeAssistant 设备标识，为空时使用 ID
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+	SwVersion    string   `json:"sw_version"`
+	ViaDevice    string   `json:"via_device"` // 所经网关的设备标识，子设备为空时使用主设备
+}
+
+// withDefaults 为空字段填充默认值
+func (d Device) withDefaults(name string) Device {
+	if d.Name == "" {
+		d.Name = name
+	}
+	if d.Manufacturer == "" {
+		d.Manufacturer = "HaPerfMonitor"
+	}
+	if d.Model == "" {
+		d.Model = "MQTT Monitor"
+	}
+	if d.SwVersion == "" {
+		d.SwVersion = "1.0"
+	}
+	return d
+}
+
+// identifiers HomeAssistant 设备标识
+func (d *Device) identifiers() []string {
+	if len(d.Identifiers) > 0 {
+		return d.Identifiers
+	}
+	return []string{d.ID}
+}
+
+// deviceState 子设备及其可用性
+type deviceState struct {
+	device    *Device
+	available bool
+}
+
+// resolveDevice 返回实体所属的设备，nil 表示主设备
+func (c *MQTTClient) resolveDevice(dev *Device) *Device {
+	if dev == nil {
+		return &c.device
+	}
+	return dev
+}
+
+// nodeID 设备在主题中使用的节点ID
+func (c *MQTTClient) nodeID(dev *Device) string {
+	return c.resolveDevice(dev).ID
+}
+
+// deviceAvailabilityTopic 设备的可用性主题
+func (c *MQTTClient) deviceAvailabilityTopic(dev *Device) string {
+	return c.topics.availability(c.nodeID(dev))
+}
+
+// devicePayload 自动发现配置中的 device 字段
+func (c *MQTTClient) devicePayload(dev *Device) map[string]any {
+	d := c.resolveDevice(dev)
+	payload := map[string]any{
+		"identifiers":  d.identifiers(),
+		"name":         d.Name,
+		"manufacturer": d.Manufacturer,
+		"model":        d.Model,
+		"sw_version":   d.SwVersion,
+	}
+	if d != &c.device {
+		via := d.ViaDevice
+		if via == "" {
+			via = c.device.identifiers()[0]
+		}
+		payload["via_device"] = via
+	}
+	return payload
+}
+
+// availabilityPayload 实体的可用性配置
+// 子设备的实体同时依赖主设备(网关)和自身的可用性主题
+func (c *MQTTClient) availabilityPayload(dev *Device) []map[string]any {
+	topics := []string{c.getAvailabilityTopic()}
+	if dev != nil && dev != &c.device {
+		topics = append(topics, c.deviceAvailabilityTopic(dev))
+	}
+	availability := make([]map[string]any, 0, len(topics))
+	for _, topic := range topics {
+		availability = append(availability, map[string]any{
+			"topic":                 topic,
+			"payload_available":     PayloadAvailable,
+			"payload_not_available": PayloadNotAvailable,
+		})
+	}
+	return availability
+}
+
+// AddDevice 注册子设备，注册后默认在线
+// 实体的 Device 字段引用未注册的设备时会自动注册
+func (c *MQTTClient) AddDevice(dev *Device) error {
+	if dev == nil || dev.ID == "" {
+		return fmt.Errorf("设备ID不能为空")
+	}
+	// 设备ID用作主题中的节点ID，HomeAssistant 只接受 [a-zA-Z0-9_-]
+	if invalidNodeIDChars.MatchString(dev.ID) {
+		return fmt.Errorf("设备ID只能包含字母、数字、_ 和 -: %s", dev.ID)
+	}
+	if dev.ID == c.device.ID {
+		return fmt.Errorf("设备ID与主设备冲突: %s", dev.ID)
+	}
+	c.mu.Lock()
+	if state, ok := c.devices[dev.ID]; ok {
+		c.mu.Unlock()
+		if state.device != dev {
+			return fmt.Errorf("设备已存在: %s", dev.ID)
+		}
+		return nil
+	}
+	c.devices[dev.ID] = &deviceState{device: dev, available: true}
+	c.mu.Unlock()
+	if c.discoveryMode == DiscoveryModeDevice {
+		c.MqttSetTopicHandlers(map[string]mqtt.MessageHandler{c.topics.legacyConfigs(dev.ID): c.handleLegacyConfig})
+		// 等待新订阅的旧配置到齐后再发布子设备的配置
+		if c.client != nil && c.client.IsConnected() {
+			c.deferDiscovery()
+		}
+	}
+	c.publishDeviceAvailability(dev, true)
+	return nil
+}
+
+// ensureDevice 确保实体引用的子设备已注册
+func (c *MQTTClient) ensureDevice(dev *Device) error {
+	if dev == nil || dev == &c.device {
+		return nil
+	}
+	return c.AddDevice(dev)
+}
+
+// SetDeviceAvailable 设置子设备是否在线，离线时 HomeAssistant 中该设备的实体显示为不可用
+func (c *MQTTClient) SetDeviceAvailable(dev *Device, available bool) error {
+	if dev == nil {
+		return fmt.Errorf("设备不能为空")
+	}
+	c.mu.Lock()
+	state, ok := c.devices[dev.ID]
+	if ok {
+		state.available = available
+	}
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("设备不存在: %s", dev.ID)
+	}
+	c.publishDeviceAvailability(dev, available)
+	return nil
+}
+
+func (c *MQTTClient) publishDeviceAvailability(dev *Device, available bool) {
+	if c.client == nil || !c.client.IsConnected() {
+		return
+	}
+	payload := PayloadNotAvailable
+	if available {
+		payload = PayloadAvailable
+	}
+	token := c.client.Publish(c.deviceAvailabilityTopic(dev), 1, true, payload)
+	token.Wait()
+}
+
+// publishDevicesAvailability 重新发布全部子设备的可用性
+func (c *MQTTClient) publishDevicesAvailability() {
+	c.mu.Lock()
+	states := make([]deviceState, 0, len(c.devices))
+	for _, state := range c.devices {
+		states = append(states, *state)
+	}
+	c.mu.Unlock()
+	for _, state := range states {
+		c.publishDeviceAvailability(state.device, state.available)
+	}
+}
@@ -0,0 +1,261 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// DefaultMaxAnnounceDelay 收到 HomeAssistant 上线消息后重新发布前的默认最大随机延迟
+// HomeAssistant 的出生主题默认为 <discovery_prefix>/status，见 topicBuilder.birth
+const DefaultMaxAnnounceDelay = 5 * time.Second
+
+// legacySettleDelay 设备模式下订阅旧配置主题后等待代理推送保留消息的时间
+const legacySettleDelay = time.Second
+
+// 自动发现配置的发布方式
+const (
+	DiscoveryModeEntity = "entity" // 每个实体单独发布配置(默认)
+	DiscoveryModeDevice = "device" // 每台设备发布一条包含全部实体的配置，需要 HomeAssistant 2024.11 及以上
+)
+
+// entityConfig 实体及其自动发现配置
+type entityConfig struct {
+	entity  MqttEntity
+	payload map[string]any
+}
+
+// publishConfig 发布单个实体的自动发现配置
+func (c *MQTTClient) publishConfig(entity MqttEntity, payload map[string]any) {
+	c.publishConfigs([]entityConfig{{entity, payload}})
+}
+
+// publishConfigs 发布实体的自动发现配置，设备模式下重新发布实体所属设备的配置
+func (c *MQTTClient) publishConfigs(configs []entityConfig) {
+	if c.discoveryMode != DiscoveryModeDevice {
+		for _, cfg := range configs {
+			jsonData, _ := json.MarshalIndent(cfg.payload, "", "  ")
+			token := c.client.Publish(c.getTopic(cfg.entity), 1, true, string(jsonData))
+			token.Wait()
+		}
+		return
+	}
+	c.migrateMu.Lock()
+	defer c.migrateMu.Unlock()
+	c.mu.Lock()
+	settling := c.settling
+	c.mu.Unlock()
+	// 等待期结束后迁移旧配置并统一发布
+	if settling {
+		return
+	}
+	published := map[*Device]bool{}
+	for _, cfg := range configs {
+		dev := c.resolveDevice(cfg.entity.Device)
+		if !published[dev] {
+			published[dev] = true
+			c.publishDeviceConfig(dev, c.discoveryConfigs())
+		}
+	}
+}
+
+// discoveryConfigs 全部实体（默认实体、采集器、注册的传感器）的自动发现配置
+func (c *MQTTClient) discoveryConfigs() []entityConfig {
+	var configs []entityConfig
+	for _, entity := range c.defaultEntities {
+		configs = append(configs, entityConfig{entity, c.getPayload(entity)})
+	}
+	for _, col := range c.collectors.all() {
+		configs = append(configs, c.collectorConfigs(col)...)
+	}
+	c.mu.Lock()
+	sensors := append([]*registeredEntity(nil), c.sensors...)
+	c.mu.Unlock()
+	for _, reg := range sensors {
+		configs = append(configs, entityConfig{reg.entity, c.getPayload(reg.entity)})
+	}
+	return configs
+}
+
+// publishDiscovery 发布全部实体的自动发现配置
+// 设备模式下先按 HomeAssistant 的迁移流程处理代理上保留的旧配置：发布迁移标记、发布设备配置、清除旧配置
+// 没有对应实体的旧配置直接清除，HomeAssistant 随之删除这些实体
+func (c *MQTTClient) publishDiscovery() {
+	if c.discoveryMode != DiscoveryModeDevice {
+		c.publishConfigs(c.discoveryConfigs())
+		return
+	}
+	if c.client == nil || !c.client.IsConnected() {
+		return
+	}
+	c.migrateMu.Lock()
+	defer c.migrateMu.Unlock()
+	c.mu.Lock()
+	legacy := c.legacyTopics
+	c.legacyTopics = map[string]bool{}
+	c.settling = false
+	devices := []*Device{&c.device}
+	for _, state := range c.devices {
+		devices = append(devices, state.device)
+	}
+	c.mu.Unlock()
+
+	configs := c.discoveryConfigs()
+	var migrate []string
+	for _, cfg := range configs {
+		if topic := c.getTopic(cfg.entity); legacy[topic] {
+			migrate = append(migrate, topic)
+		}
+	}
+	c.publishRetained(migrate, `{"migrate_discovery": true}`)
+	for _, dev := range devices {
+		c.publishDeviceConfig(dev, configs)
+	}
+	c.publishRetained(slices.Sorted(maps.Keys(legacy)), "")
+}
+
+// deferDiscovery 设备模式下推迟发布设备配置，等待代理推送保留的旧配置后统一迁移和发布
+func (c *MQTTClient) deferDiscovery() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.settling {
+		return
+	}
+	c.settling = true
+	time.AfterFunc(legacySettleDelay, c.publishDiscovery)
+}
+
+// handleLegacyConfig 收到代理保留的旧的按实体配置，记录后在等待期结束时统一迁移
+// 只处理订阅时收到的保留消息，迁移完成后旧主题被清空，重启后不会再次迁移
+func (c *MQTTClient) handleLegacyConfig(client mqtt.Client, msg mqtt.Message) {
+	if !msg.Retained() || len(msg.Payload()) == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.legacyTopics[msg.Topic()] = true
+	c.mu.Unlock()
+	c.deferDiscovery()
+}
+
+// publishRetained 向多个主题发布保留消息
+func (c *MQTTClient) publishRetained(topics []string, payload string) {
+	for _, topic := range topics {
+		token := c.client.Publish(topic, 1, true, payload)
+		token.Wait()
+	}
+}
+
+// deviceConfigTopic 设备模式下设备的自动发现主题
+func (c *MQTTClient) deviceConfigTopic(dev *Device) string {
+	return c.topics.deviceConfig(c.nodeID(dev))
+}
+
+// originPayload 自动发现配置中的 origin 字段，描述发布配置的 hamqtt 而不是设备
+func (c *MQTTClient) originPayload() map[string]any {
+	origin := map[string]any{
+		"name":        "hamqtt",
+		"support_url": "https://github.com/LanSilence/hamqtt",
+	}
+	if c.version != "" {
+		origin["sw_version"] = c.version
+	}
+	return origin
+}
+
+// publishDeviceConfig 设备模式下发布一台设备的全部实体
+// 已注销的实体只保留 platform 字段，HomeAssistant 收到后删除该实体
+func (c *MQTTClient) publishDeviceConfig(dev *Device, configs []entityConfig) {
+	node := c.nodeID(dev)
+	components := map[string]any{}
+	for _, cfg := range configs {
+		if c.resolveDevice(cfg.entity.Device) != dev {
+			continue
+		}
+		component := maps.Clone(cfg.payload)
+		delete(component, "device")
+		component["platform"] = cfg.entity.component()
+		components[component["unique_id"].(string)] = component
+	}
+	c.mu.Lock()
+	for id, platform := range c.removedComponents[node] {
+		if _, ok := components[id]; !ok {
+			components[id] = map[string]any{"platform": platform}
+		}
+	}
+	c.mu.Unlock()
+	if len(components) == 0 {
+		return
+	}
+	payload := map[string]any{
+		"device":     c.devicePayload(dev),
+		"origin":     c.originPayload(),
+		"components": components,
+	}
+	jsonData, _ := json.MarshalIndent(payload, "", "  ")
+	token := c.client.Publish(c.deviceConfigTopic(dev), 1, true, string(jsonData))
+	token.Wait()
+}
+
+// announce 重新发布全部自动发现配置，并立即发布一次当前状态
+func (c *MQTTClient) announce() {
+	if c.client == nil || !c.client.IsConnected() {
+		return
+	}
+	c.publishDevicesAvailability()
+	c.publishDiscovery()
+	c.resetGates()
+	c.scheduler.triggerAll()
+}
+
+// onConnect 每次(重新)连接成功后调用
+// 代理未开启持久化时，断线期间保留的配置和状态可能已丢失，需全部重新发布
+// 设备模式下等待订阅时推送的旧配置到齐后再发布，见 publishDiscovery
+func (c *MQTTClient) onConnect(client mqtt.Client) {
+	client.Publish(c.getAvailabilityTopic(), 1, true, PayloadAvailable)
+	delay := time.Duration(0)
+	if c.discoveryMode == DiscoveryModeDevice {
+		delay = legacySettleDelay
+		c.mu.Lock()
+		c.settling = true
+		c.mu.Unlock()
+	}
+	c.mu.Lock()
+	handlers := maps.Clone(c.handlers)
+	c.mu.Unlock()
+	for topic, handler := range handlers {
+		token := client.Subscribe(topic, 1, handler)
+		token.Wait()
+		if token.Error() != nil {
+			fmt.Println("订阅失败:", topic, token.Error())
+		} else {
+			fmt.Println("已订阅主题:", topic)
+		}
+	}
+	// 不能在连接回调中阻塞等待发布完成
+	go func() {
+		time.Sleep(delay)
+		c.announce()
+	}()
+}
+
+// handleBirthMessage HomeAssistant 重启后重新发布配置，随机延迟避免大量主机同时发布
+func (c *MQTTClient) handleBirthMessage(client mqtt.Client, msg mqtt.Message) {
+	if string(msg.Payload()) != PayloadAvailable {
+		return
+	}
+	delay := time.Duration(0)
+	if c.maxAnnounceDelay > 0 {
+		delay = time.Duration(rand.Int63n(int64(c.maxAnnounceDelay)))
+	}
+	fmt.Printf("HomeAssistant 已上线，%v 后重新发布自动发现配置\n", delay.Round(time.Millisecond))
+	// 不能在消息回调中阻塞等待发布完成
+	go func() {
+		time.Sleep(delay)
+		c.announce()
+	}()
+}
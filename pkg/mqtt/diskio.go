@@ -0,0 +1,113 @@
+package mqtt
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+)
+
+// CollectorDiskIO 按块设备发布读写速率、IOPS 和繁忙度的内置采集器
+const CollectorDiskIO = "diskio"
+
+// DiskIOFilter 按设备名筛选块设备，模式使用 path.Match 语法(如 sd*)
+// Include 为空时只包含整块磁盘(Linux 下 /sys/block 中的设备)，Exclude 优先于 Include
+type DiskIOFilter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"` // 为 nil 时排除 DefaultDiskIOExclude
+}
+
+// DefaultDiskIOExclude 默认排除的块设备
+var DefaultDiskIOExclude = []string{"loop*", "ram*", "zram*"}
+
+func (f DiskIOFilter) match(name string) bool {
+	exclude := f.Exclude
+	if exclude == nil {
+		exclude = DefaultDiskIOExclude
+	}
+	if matchAny(exclude, name) {
+		return false
+	}
+	if len(f.Include) > 0 {
+		return matchAny(f.Include, name)
+	}
+	// 分区的计数已包含在所在磁盘中
+	if _, err := os.Stat("/sys/block"); err == nil {
+		_, err := os.Stat(filepath.Join("/sys/block", name))
+		return err == nil
+	}
+	return true
+}
+
+// newDiskIOCollector 每个块设备发布读写速率、IOPS 和繁忙度
+func newDiskIOCollector(filter DiskIOFilter) *dynamicCollector {
+	var counters counterRates
+	return newDynamicCollector(CollectorDiskIO, func() (map[string]any, []MqttEntity, error) {
+		stats, err := disk.IOCounters()
+		if err != nil {
+			return nil, nil, err
+		}
+		names := make([]string, 0, len(stats))
+		for name := range stats {
+			if filter.match(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		counters.begin(time.Now())
+		defer counters.end()
+		data := map[string]any{}
+		var entities []MqttEntity
+		for _, name := range names {
+			id := "diskio_" + entityID(name)
+			cur := stats[name]
+			rates, ok := counters.rates(name, map[string]uint64{
+				"read": cur.ReadBytes, "write": cur.WriteBytes,
+				"read_iops": cur.ReadCount, "write_iops": cur.WriteCount,
+				"io_time": cur.IoTime,
+			})
+			if counters.ready(name) {
+				entities = append(entities, diskIOEntities(id, name)...)
+			}
+			if !ok {
+				continue
+			}
+			data[id+"_read"] = round1(rates["read"])
+			data[id+"_write"] = round1(rates["write"])
+			data[id+"_read_iops"] = round1(rates["read_iops"])
+			data[id+"_write_iops"] = round1(rates["write_iops"])
+			// IoTime 为设备有请求在处理的累计毫秒数
+			data[id+"_busy"] = round1(min(rates["io_time"]/10, 100))
+		}
+		return data, entities, nil
+	})
+}
+
+func diskIOEntities(id, device string) []MqttEntity {
+	rate := func(suffix, description, deviceClass, unit, icon string) MqttEntity {
+		return MqttEntity{
+			Name:              id + "_" + suffix,
+			Description:       device + " " + description,
+			Component:         "sensor",
+			DeviceClass:       deviceClass,
+			UnitOfMeasurement: unit,
+			ValueTemplate:     "value_json." + id + "_" + suffix,
+			StateClass:        StateClassMeasurement,
+			Icon:              icon,
+		}
+	}
+	read := rate("read", "Read", "data_rate", "B/s", "")
+	write := rate("write", "Write", "data_rate", "B/s", "")
+	read.OtherConfig = map[string]any{"suggested_unit_of_measurement": "MB/s"}
+	write.OtherConfig = map[string]any{"suggested_unit_of_measurement": "MB/s"}
+	return []MqttEntity{
+		read,
+		write,
+		rate("read_iops", "Read IOPS", "", "ops/s", "mdi:harddisk"),
+		rate("write_iops", "Write IOPS", "", "ops/s", "mdi:harddisk"),
+		rate("busy", "Busy", "", "%", "mdi:gauge"),
+	}
+}
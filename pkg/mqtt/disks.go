@@ -0,0 +1,126 @@
+package mqtt
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/LanSilence/hamqtt/internal/system"
+)
+
+// CollectorDisks 按挂载点发布磁盘使用情况的内置采集器
+const CollectorDisks = "disks"
+
+// DiskFilter 按挂载点和文件系统类型筛选磁盘，模式使用 path.Match 语法(如 /snap/*)
+// Include 为空时包含全部，Exclude 优先于 Include
+type DiskFilter struct {
+	IncludeMounts  []string `json:"include_mounts"`
+	ExcludeMounts  []string `json:"exclude_mounts"`
+	IncludeFSTypes []string `json:"include_fstypes"`
+	ExcludeFSTypes []string `json:"exclude_fstypes"` // 为 nil 时排除 DefaultExcludeFSTypes
+}
+
+// DefaultExcludeFSTypes 默认排除的文件系统类型
+var DefaultExcludeFSTypes = []string{"tmpfs", "devtmpfs", "squashfs", "overlay", "iso9660"}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// match 磁盘是否需要发布
+func (f DiskFilter) match(d system.DiskInfo) bool {
+	excludeFSTypes := f.ExcludeFSTypes
+	if excludeFSTypes == nil {
+		excludeFSTypes = DefaultExcludeFSTypes
+	}
+	if matchAny(f.ExcludeMounts, d.Mountpoint) || matchAny(excludeFSTypes, d.Fstype) {
+		return false
+	}
+	if len(f.IncludeMounts) > 0 && !matchAny(f.IncludeMounts, d.Mountpoint) {
+		return false
+	}
+	if len(f.IncludeFSTypes) > 0 && !matchAny(f.IncludeFSTypes, d.Fstype) {
+		return false
+	}
+	return true
+}
+
+var invalidEntityChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// entityID 转换为实体名称可用的标识，如挂载点 / -> root, /var/lib -> var_lib, C:\ -> c
+func entityID(mountpoint string) string {
+	id := strings.Trim(invalidEntityChars.ReplaceAllString(mountpoint, "_"), "_")
+	if id == "" {
+		return "root"
+	}
+	return strings.ToLower(id)
+}
+
+// newDiskCollector 每个挂载点发布使用率、可用和总容量三个传感器，实体随挂载和卸载变化
+func newDiskCollector(filter DiskFilter) *dynamicCollector {
+	return newDynamicCollector(CollectorDisks, func() (map[string]any, []MqttEntity, error) {
+		disks, err := system.GetAllDisksInfo()
+		if err != nil {
+			return nil, nil, err
+		}
+		data := map[string]any{}
+		var entities []MqttEntity
+		for _, info := range disks {
+			if !filter.match(info) {
+				continue
+			}
+			id := "disk_" + entityID(info.Mountpoint)
+			if _, ok := data[id]; ok {
+				// 同一挂载点挂载了多次，只发布第一个
+				continue
+			}
+			data[id] = info
+			data[id+"_used"] = info.UsedPercent
+			data[id+"_free"] = info.Free
+			data[id+"_total"] = info.Total
+			entities = append(entities, diskEntities(id, info.Mountpoint)...)
+		}
+		return data, entities, nil
+	})
+}
+
+func diskEntities(id, mountpoint string) []MqttEntity {
+	bytesConfig := map[string]any{"suggested_unit_of_measurement": "GiB"}
+	return []MqttEntity{
+		{
+			Name:                   id + "_used",
+			Description:            "Disk " + mountpoint + " Usage",
+			Component:              "sensor",
+			UnitOfMeasurement:      "%",
+			ValueTemplate:          "value_json." + id + "_used",
+			StateClass:             StateClassMeasurement,
+			Icon:                   "mdi:harddisk",
+			JSONAttributesTemplate: "{{ value_json." + id + " | tojson }}",
+		},
+		{
+			Name:              id + "_free",
+			Description:       "Disk " + mountpoint + " Free",
+			Component:         "sensor",
+			DeviceClass:       "data_size",
+			UnitOfMeasurement: "B",
+			ValueTemplate:     "value_json." + id + "_free",
+			StateClass:        StateClassMeasurement,
+			OtherConfig:       bytesConfig,
+		},
+		{
+			Name:              id + "_total",
+			Description:       "Disk " + mountpoint + " Total",
+			Component:         "sensor",
+			DeviceClass:       "data_size",
+			UnitOfMeasurement: "B",
+			ValueTemplate:     "value_json." + id + "_total",
+			EntityCategory:    EntityCategoryDiagnostic,
+			OtherConfig:       bytesConfig,
+		},
+	}
+}
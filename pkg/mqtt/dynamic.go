@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"sync"
+	"time"
+)
+
+// dynamicCollector 实体随采集结果变化的采集器(如磁盘、网络接口、hwmon 传感器)
+// scan 每次返回采集数据和当前的实体列表，客户端每次采集后同步自动发现配置
+type dynamicCollector struct {
+	name     string
+	interval time.Duration // 为0时使用默认发布间隔
+	scan     func() (map[string]any, []MqttEntity, error)
+	mu       sync.Mutex
+	entities []MqttEntity
+}
+
+func newDynamicCollector(name string, scan func() (map[string]any, []MqttEntity, error)) *dynamicCollector {
+	return &dynamicCollector{name: name, scan: scan}
+}
+
+func (d *dynamicCollector) Name() string { return d.name }
+
+func (d *dynamicCollector) Interval() time.Duration { return d.interval }
+
+func (d *dynamicCollector) Entities() []MqttEntity {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.entities
+}
+
+func (d *dynamicCollector) Collect() (map[string]any, error) {
+	data, entities, err := d.scan()
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.entities = entities
+	d.mu.Unlock()
+	return data, nil
+}
+
+// counterRates 由相邻两次采样的累计计数器差值计算每秒速率，首次采样只记录计数器
+// 每次采样依次调用 begin、每个对象的 rates 和 end，调用方需保证不会并发采样
+type counterRates struct {
+	last      map[string]map[string]uint64
+	next      map[string]map[string]uint64
+	lastReady map[string]bool // 已算出过速率的对象
+	nextReady map[string]bool
+	lastTime  time.Time
+	elapsed   float64
+}
+
+// begin 开始一次采样
+func (r *counterRates) begin(now time.Time) {
+	r.elapsed = now.Sub(r.lastTime).Seconds()
+	r.lastTime = now
+	r.next = map[string]map[string]uint64{}
+	r.nextReady = map[string]bool{}
+}
+
+// rates 记录对象的计数器并返回各计数器的每秒速率
+// 没有上次的记录，或任一计数器变小(回绕、设备重建)时返回 false
+func (r *counterRates) rates(id string, counters map[string]uint64) (map[string]float64, bool) {
+	r.next[id] = counters
+	if r.lastReady[id] {
+		r.nextReady[id] = true
+	}
+	prev, ok := r.last[id]
+	if !ok || r.elapsed <= 0 {
+		return nil, false
+	}
+	result := make(map[string]float64, len(counters))
+	for key, cur := range counters {
+		last, ok := prev[key]
+		if !ok || cur < last {
+			return nil, false
+		}
+		result[key] = float64(cur-last) / r.elapsed
+	}
+	r.nextReady[id] = true
+	return result, true
+}
+
+// ready 对象是否已算出过速率，在本次采样的 rates 之后调用
+// 速率实体在此之后才发布自动发现配置，避免 HomeAssistant 的模板找不到字段，计数器回绕时不会移除
+func (r *counterRates) ready(id string) bool {
+	return r.nextReady[id]
+}
+
+// end 结束采样，本次未出现的对象不再保留
+func (r *counterRates) end() {
+	r.last = r.next
+	r.next = nil
+	r.lastReady = r.nextReady
+	r.nextReady = nil
+}
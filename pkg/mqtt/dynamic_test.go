@@ -0,0 +1,63 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterRates(t *testing.T) {
+	var r counterRates
+	start := time.Now()
+	sample := func(after time.Duration, counters map[string]uint64) (map[string]float64, bool) {
+		r.begin(start.Add(after))
+		defer r.end()
+		return r.rates("eth0", counters)
+	}
+
+	if _, ok := sample(0, map[string]uint64{"rx": 1000, "tx": 500}); ok || r.lastReady["eth0"] {
+		t.Fatal("首次采样不应有速率")
+	}
+	rates, ok := sample(2*time.Second, map[string]uint64{"rx": 3000, "tx": 500})
+	if !ok || rates["rx"] != 1000 || rates["tx"] != 0 || !r.lastReady["eth0"] {
+		t.Fatalf("rates = %v, %v, want rx=1000 tx=0", rates, ok)
+	}
+	// 计数器回绕
+	if _, ok := sample(3*time.Second, map[string]uint64{"rx": 10, "tx": 600}); ok {
+		t.Fatal("计数器变小时不应有速率")
+	}
+	if !r.lastReady["eth0"] {
+		t.Fatal("计数器回绕后仍应保留速率实体")
+	}
+	rates, ok = sample(4*time.Second, map[string]uint64{"rx": 110, "tx": 600})
+	if !ok || rates["rx"] != 100 {
+		t.Fatalf("回绕后 rates = %v, %v, want rx=100", rates, ok)
+	}
+
+	// 某次采样中消失的对象不再保留上次的计数器
+	r.begin(start.Add(5 * time.Second))
+	r.end()
+	if _, ok := sample(6*time.Second, map[string]uint64{"rx": 200, "tx": 700}); ok || r.lastReady["eth0"] {
+		t.Fatal("对象重新出现时不应有速率")
+	}
+}
+
+func TestDynamicCollectorEntities(t *testing.T) {
+	entities := []MqttEntity{{Name: "a"}}
+	col := newDynamicCollector("test", func() (map[string]any, []MqttEntity, error) {
+		return map[string]any{"a": 1}, entities, nil
+	})
+	if len(col.Entities()) != 0 {
+		t.Fatal("采集前不应有实体")
+	}
+	if _, err := col.Collect(); err != nil {
+		t.Fatal(err)
+	}
+	entities = append(entities, MqttEntity{Name: "b"})
+	if got := col.Entities(); len(got) != 1 {
+		t.Fatalf("Entities() = %v, want 上次采集的 1 个实体", got)
+	}
+	col.Collect()
+	if got := col.Entities(); len(got) != 2 {
+		t.Fatalf("Entities() = %v, want 2 个实体", got)
+	}
+}
@@ -0,0 +1,235 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// registeredEntity 通过 RegisterSensor 注册的实体及其回调
+type registeredEntity struct {
+	entity         MqttEntity
+	commandHandler mqtt.MessageHandler
+	stateHandler   func() interface{}
+	reregister     func(MqttEntity) error // 类型化注册函数(RegisterLight 等)的重新注册回调
+	commandTopics  []string               // 已订阅的命令主题
+	jobs           []string               // 调度器中的状态发布任务
+}
+
+// replaceSensor 添加实体，返回被替换的同名实体，调用方需持有 c.mu
+func (c *MQTTClient) replaceSensor(reg *registeredEntity) *registeredEntity {
+	key := c.entityKey(reg.entity)
+	for i, existing := range c.sensors {
+		if c.entityKey(existing.entity) == key {
+			c.sensors[i] = reg
+			return existing
+		}
+	}
+	c.sensors = append(c.sensors, reg)
+	return nil
+}
+
+// findSensor 按名称和设备查找已注册的实体，调用方需持有 c.mu
+func (c *MQTTClient) findSensor(entity MqttEntity) (int, *registeredEntity) {
+	key := c.entityKey(entity)
+	for i, reg := range c.sensors {
+		if c.entityKey(reg.entity) == key {
+			return i, reg
+		}
+	}
+	return -1, nil
+}
+
+// attachEntity 发布实体配置，订阅命令主题并添加状态发布任务
+func (c *MQTTClient) attachEntity(reg *registeredEntity) {
+	entity := reg.entity
+	payload := c.getPayload(entity)
+	if c.client != nil && c.client.IsConnected() {
+		c.publishConfig(entity, payload)
+	}
+
+	// 注册命令处理handler，未连接时在连接成功后统一订阅
+	if topic, ok := payload["command_topic"].(string); ok && reg.commandHandler != nil {
+		reg.commandTopics = append(reg.commandTopics, topic)
+		c.MqttSetTopicHandlers(map[string]mqtt.MessageHandler{topic: reg.commandHandler})
+	}
+
+	// 注册状态更新处理
+	if reg.stateHandler != nil {
+		key := "sensor/" + c.entityKey(entity)
+		topic := payload["state_topic"].(string)
+		gate := c.newGate(key, []MqttEntity{entity}, true)
+		reg.jobs = append(reg.jobs, key)
+		c.schedule(key, entity.Interval, func() {
+			state := reg.stateHandler()
+			if !gate.allow(map[string]any{"": state}, time.Now()) {
+				return
+			}
+			payload, _ := json.Marshal(state)
+			c.client.Publish(topic, 1, true, payload)
+		})
+	}
+	if key := c.scheduleAttributes(entity, entity.Interval, nil); key != "" {
+		reg.jobs = append(reg.jobs, key)
+	}
+}
+
+// attributesTopic 实体的 JSON 属性主题
+func (c *MQTTClient) attributesTopic(entity MqttEntity) string {
+	if entity.JSONAttributesTopic != "" {
+		return entity.JSONAttributesTopic
+	}
+	return c.topics.entity(entity.component(), c.nodeID(entity.Device), entity.Name, "attributes")
+}
+
+// attributesJobKey 实体属性发布任务在调度器中的键
+func (c *MQTTClient) attributesJobKey(entity MqttEntity) string {
+	return "attributes/" + c.entityKey(entity)
+}
+
+// scheduleAttributes 为设置了 Attributes 回调的实体添加属性发布任务，返回任务键
+// enabled 为可选的启用判断，返回 false 时跳过本次发布
+func (c *MQTTClient) scheduleAttributes(entity MqttEntity, interval time.Duration, enabled func() bool) string {
+	if entity.Attributes == nil {
+		return ""
+	}
+	key := c.attributesJobKey(entity)
+	topic := c.attributesTopic(entity)
+	c.schedule(key, interval, func() {
+		if enabled != nil && !enabled() {
+			return
+		}
+		payload, err := json.Marshal(entity.Attributes())
+		if err != nil {
+			fmt.Println("属性序列化失败:", entity.Name, err)
+			return
+		}
+		c.client.Publish(topic, 1, true, payload)
+	})
+	return key
+}
+
+// detachEntity 停止状态发布任务，取消订阅命令主题并移除回调
+func (c *MQTTClient) detachEntity(reg *registeredEntity) {
+	for _, key := range reg.jobs {
+		c.scheduler.remove(key)
+	}
+	c.mu.Lock()
+	for _, key := range reg.jobs {
+		delete(c.gates, key)
+	}
+	for _, topic := range reg.commandTopics {
+		delete(c.handlers, topic)
+	}
+	c.mu.Unlock()
+	if len(reg.commandTopics) > 0 && c.client != nil && c.client.IsConnected() {
+		token := c.client.Unsubscribe(reg.commandTopics...)
+		token.Wait()
+		if token.Error() != nil {
+			fmt.Println("取消订阅失败:", reg.commandTopics, token.Error())
+		}
+	}
+	reg.jobs = nil
+	reg.commandTopics = nil
+}
+
+// PublishState 立即发布实体状态，state 按 JSON 编码后发布到实体的状态主题
+func (c *MQTTClient) PublishState(entity MqttEntity, state any) error {
+	if c.client == nil || !c.client.IsConnected() {
+		return fmt.Errorf("MQTT未连接")
+	}
+	topic, ok := c.getPayload(entity)["state_topic"].(string)
+	if !ok {
+		return fmt.Errorf("实体没有状态主题: %s", entity.Name)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	token := c.client.Publish(topic, 1, true, data)
+	token.Wait()
+	return token.Error()
+}
+
+// deleteConfig 发布空的保留配置，HomeAssistant 收到后删除该实体
+// 设备模式下重新发布设备配置，调用前实体需已从 c.sensors 中移除
+func (c *MQTTClient) deleteConfig(entity MqttEntity) {
+	if c.discoveryMode == DiscoveryModeDevice {
+		node := c.nodeID(entity.Device)
+		c.mu.Lock()
+		if c.removedComponents[node] == nil {
+			c.removedComponents[node] = map[string]string{}
+		}
+		c.removedComponents[node][c.getPayload(entity)["unique_id"].(string)] = entity.component()
+		c.mu.Unlock()
+	}
+	if c.client == nil || !c.client.IsConnected() {
+		return
+	}
+	if c.discoveryMode == DiscoveryModeDevice {
+		c.publishDeviceConfig(c.resolveDevice(entity.Device), c.discoveryConfigs())
+		return
+	}
+	token := c.client.Publish(c.getTopic(entity), 1, true, "")
+	token.Wait()
+}
+
+// UnregisterEntity 注销通过 RegisterSensor/RegisterLight 注册的实体，按 Name 和 Device 匹配
+// 会从 HomeAssistant 中删除该实体，停止状态发布并取消订阅命令主题
+func (c *MQTTClient) UnregisterEntity(entity MqttEntity) error {
+	c.mu.Lock()
+	i, reg := c.findSensor(entity)
+	if reg != nil {
+		c.sensors = append(c.sensors[:i], c.sensors[i+1:]...)
+	}
+	c.mu.Unlock()
+	if reg == nil {
+		return fmt.Errorf("实体不存在: %s", c.entityKey(entity))
+	}
+	c.detachEntity(reg)
+	c.deleteConfig(reg.entity)
+	return nil
+}
+
+// UpdateEntity 更新已注册实体的配置，按 Name 和 Device 匹配，沿用原有的命令和状态回调
+// Component、ValueTemplate 为空时沿用原值；组件类型变化时会先删除旧的自动发现配置
+// 通过 RegisterLight 等类型化函数注册的实体不能修改组件类型，按新的 ExternalOptions 重新注册
+func (c *MQTTClient) UpdateEntity(entity MqttEntity) error {
+	c.mu.Lock()
+	_, old := c.findSensor(entity)
+	var reg *registeredEntity
+	if old != nil {
+		if entity.Component == "" {
+			entity.Component = old.entity.Component
+		}
+		if entity.ValueTemplate == "" {
+			entity.ValueTemplate = old.entity.ValueTemplate
+		}
+		if old.reregister == nil {
+			reg = &registeredEntity{
+				entity:         entity,
+				commandHandler: old.commandHandler,
+				stateHandler:   old.stateHandler,
+			}
+			c.replaceSensor(reg)
+		}
+	}
+	c.mu.Unlock()
+	if old == nil {
+		return fmt.Errorf("实体不存在: %s", c.entityKey(entity))
+	}
+	if reg == nil {
+		if entity.Component != old.entity.Component {
+			return fmt.Errorf("不能修改实体的组件类型: %s %s -> %s", entity.Name, old.entity.Component, entity.Component)
+		}
+		return old.reregister(entity)
+	}
+	c.detachEntity(old)
+	if c.getTopic(old.entity) != c.getTopic(entity) {
+		c.deleteConfig(old.entity)
+	}
+	c.attachEntity(reg)
+	return nil
+}
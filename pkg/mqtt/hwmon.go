@@ -0,0 +1,57 @@
+package mqtt
+
+import (
+	"strconv"
+
+	"github.com/LanSilence/hamqtt/internal/system"
+)
+
+// CollectorHwmon 发布 Linux hwmon 全部温度、风扇和电压的内置采集器
+const CollectorHwmon = "hwmon"
+
+// newHwmonCollector 每个 hwmon 输入发布一个传感器，实体随硬件变化自动增删
+func newHwmonCollector(root string) *dynamicCollector {
+	scanner := system.HwmonScanner{Root: root}
+	return newDynamicCollector(CollectorHwmon, func() (map[string]any, []MqttEntity, error) {
+		sensors, err := scanner.Scan()
+		if err != nil {
+			return nil, nil, err
+		}
+		data := map[string]any{}
+		var entities []MqttEntity
+		for _, sensor := range sensors {
+			id := "hwmon_" + entityID(sensor.Chip+"_"+sensor.Label)
+			// 同名芯片(如多块 nvme)或重复标签时追加序号，同名芯片按所属设备排序，序号不随 hwmonN 编号变化
+			for n := 2; data[id] != nil; n++ {
+				id = "hwmon_" + entityID(sensor.Chip+"_"+sensor.Label) + "_" + strconv.Itoa(n)
+			}
+			data[id] = sensor.Value
+			data[id+"_info"] = sensor
+			entities = append(entities, hwmonEntity(id, sensor))
+		}
+		return data, entities, nil
+	})
+}
+
+func hwmonEntity(id string, sensor system.HwmonSensor) MqttEntity {
+	entity := MqttEntity{
+		Name:                   id,
+		Description:            sensor.Chip + " " + sensor.Label,
+		Component:              "sensor",
+		ValueTemplate:          "value_json." + id,
+		StateClass:             StateClassMeasurement,
+		JSONAttributesTemplate: "{{ value_json." + id + "_info | tojson }}",
+	}
+	switch sensor.Type {
+	case system.HwmonTemperature:
+		entity.DeviceClass = "temperature"
+		entity.UnitOfMeasurement = "°C"
+	case system.HwmonFan:
+		entity.UnitOfMeasurement = "RPM"
+		entity.Icon = "mdi:fan"
+	case system.HwmonVoltage:
+		entity.DeviceClass = "voltage"
+		entity.UnitOfMeasurement = "V"
+	}
+	return entity
+}
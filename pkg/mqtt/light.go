@@ -0,0 +1,319 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// HomeAssistant JSON schema 灯光支持的颜色模式
+const (
+	ColorModeOnOff      = "onoff"
+	ColorModeBrightness = "brightness"
+	ColorModeColorTemp  = "color_temp"
+	ColorModeHS         = "hs"
+	ColorModeXY         = "xy"
+	ColorModeRGB        = "rgb"
+	ColorModeRGBW       = "rgbw"
+	ColorModeRGBWW      = "rgbww"
+	ColorModeWhite      = "white"
+)
+
+var validColorModes = map[string]bool{
+	ColorModeOnOff:      true,
+	ColorModeBrightness: true,
+	ColorModeColorTemp:  true,
+	ColorModeHS:         true,
+	ColorModeXY:         true,
+	ColorModeRGB:        true,
+	ColorModeRGBW:       true,
+	ColorModeRGBWW:      true,
+	ColorModeWhite:      true,
+}
+
+// LightColor 灯光颜色，按 color_mode 只读取对应字段
+// rgb/rgbw/rgbww 使用 R/G/B(/W/C)，hs 使用 H/S，xy 使用 X/Y
+type LightColor struct {
+	R int     `json:"r"`
+	G int     `json:"g"`
+	B int     `json:"b"`
+	C int     `json:"c"`
+	W int     `json:"w"`
+	H float64 `json:"h"`
+	S float64 `json:"s"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// LightCommand HomeAssistant 下发的 JSON 灯光命令
+// 未下发的字段为 nil/空字符串
+type LightCommand struct {
+	State      string      `json:"state"`                // ON / OFF
+	Brightness *int        `json:"brightness,omitempty"` // 范围 0..brightness_scale
+	ColorMode  string      `json:"color_mode,omitempty"` // HomeAssistant 不下发，由 DecodeLightCommand 根据出现的字段推导
+	ColorTemp  *int        `json:"color_temp,omitempty"` // 单位取决于 ColorTempKelvin：mireds 或 kelvin
+	Color      *LightColor `json:"color,omitempty"`
+	White      *int        `json:"white,omitempty"`
+	Effect     string      `json:"effect,omitempty"`
+	Transition *float64    `json:"transition,omitempty"` // 渐变时间，秒
+	Flash      string      `json:"flash,omitempty"`      // short / long
+}
+
+// On 命令是否要求开灯
+func (cmd LightCommand) On() bool {
+	return cmd.State == "ON"
+}
+
+// LightState 上报给 HomeAssistant 的 JSON 灯光状态
+type LightState struct {
+	State      string      `json:"state"`
+	Brightness *int        `json:"brightness,omitempty"`
+	ColorMode  string      `json:"color_mode,omitempty"`
+	ColorTemp  *int        `json:"color_temp,omitempty"`
+	Color      *LightColor `json:"color,omitempty"`
+	White      *int        `json:"white,omitempty"`
+	Effect     string      `json:"effect,omitempty"`
+}
+
+// LightCommandHandler 处理解码后的灯光命令，返回错误时不会回写状态
+type LightCommandHandler func(cmd LightCommand) error
+
+// brightnessScale 亮度最大值，未设置时为 HomeAssistant 默认的 255
+func (o *LightOptions) brightnessScale() int {
+	if o == nil || o.Brightness_scale <= 0 {
+		return 255
+	}
+	return o.Brightness_scale
+}
+
+// DecodeLightCommand 将 HomeAssistant 下发的 JSON 命令解码为 LightCommand
+// options 用于校验亮度范围，可为 nil
+func DecodeLightCommand(payload []byte, options *LightOptions) (LightCommand, error) {
+	var cmd LightCommand
+	var raw struct {
+		Color map[string]json.RawMessage `json:"color"`
+	}
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return cmd, fmt.Errorf("灯光命令解析失败: %w", err)
+	}
+	json.Unmarshal(payload, &raw)
+	if mode := commandColorMode(cmd, raw.Color); mode != "" {
+		cmd.ColorMode = mode
+	}
+	cmd.State = strings.ToUpper(cmd.State)
+	if cmd.State != "ON" && cmd.State != "OFF" {
+		return cmd, fmt.Errorf("无效的灯光状态: %q", cmd.State)
+	}
+	if cmd.Brightness != nil && (*cmd.Brightness < 0 || *cmd.Brightness > options.brightnessScale()) {
+		return cmd, fmt.Errorf("无效的亮度: %d", *cmd.Brightness)
+	}
+	return cmd, nil
+}
+
+// commandColorMode 根据命令中出现的字段推导颜色模式，只调整亮度或开关时返回空
+func commandColorMode(cmd LightCommand, color map[string]json.RawMessage) string {
+	has := func(keys ...string) bool {
+		for _, key := range keys {
+			if _, ok := color[key]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case has("r", "g", "b", "c", "w"):
+		return ColorModeRGBWW
+	case has("r", "g", "b", "w"):
+		return ColorModeRGBW
+	case has("r", "g", "b"):
+		return ColorModeRGB
+	case has("h", "s"):
+		return ColorModeHS
+	case has("x", "y"):
+		return ColorModeXY
+	case cmd.ColorTemp != nil:
+		return ColorModeColorTemp
+	case cmd.White != nil:
+		return ColorModeWhite
+	}
+	return ""
+}
+
+// StateFromCommand 根据命令生成对应的状态，用于乐观模式下回写
+func StateFromCommand(cmd LightCommand) LightState {
+	return LightState{
+		State:      cmd.State,
+		Brightness: cmd.Brightness,
+		ColorMode:  cmd.ColorMode,
+		ColorTemp:  cmd.ColorTemp,
+		Color:      cmd.Color,
+		White:      cmd.White,
+		Effect:     cmd.Effect,
+	}
+}
+
+// MiredsToKelvin 色温单位转换
+func MiredsToKelvin(mireds int) int {
+	if mireds <= 0 {
+		return 0
+	}
+	return 1000000 / mireds
+}
+
+// KelvinToMireds 色温单位转换
+func KelvinToMireds(kelvin int) int {
+	if kelvin <= 0 {
+		return 0
+	}
+	return 1000000 / kelvin
+}
+
+// supportedColorModes 计算 supported_color_modes，未显式指定时根据 Supports* 推导
+func (o *LightOptions) supportedColorModes() []string {
+	if len(o.SupportedColorModes) > 0 {
+		return o.SupportedColorModes
+	}
+	var modes []string
+	if o.SupportsColorTemp {
+		modes = append(modes, ColorModeColorTemp)
+	}
+	if o.SupportsRGB {
+		modes = append(modes, ColorModeRGB)
+	}
+	if len(modes) == 0 && o.SupportsBrightness {
+		modes = append(modes, ColorModeBrightness)
+	}
+	return modes
+}
+
+// applyLightOptions 将 LightOptions 写入 JSON schema 灯光的发现配置
+func applyLightOptions(payload map[string]any, options *LightOptions) {
+	if options == nil {
+		return
+	}
+	var modes []string
+	brightness := options.SupportsBrightness
+	for _, mode := range options.supportedColorModes() {
+		if !validColorModes[mode] {
+			log.Println("忽略无效的颜色模式:", mode)
+			continue
+		}
+		if mode != ColorModeOnOff {
+			// 除 onoff 外所有颜色模式都隐含亮度控制
+			brightness = true
+		}
+		modes = append(modes, mode)
+	}
+	if len(modes) > 0 {
+		payload["supported_color_modes"] = modes
+	}
+	if brightness {
+		payload["brightness"] = true
+		if options.Brightness_scale > 0 {
+			payload["brightness_scale"] = options.Brightness_scale
+		}
+	}
+	if options.SupportsColorTemp || containsString(modes, ColorModeColorTemp) {
+		if options.ColorTempKelvin {
+			payload["color_temp_kelvin"] = true
+			if options.MinKelvin > 0 {
+				payload["min_kelvin"] = options.MinKelvin
+			}
+			if options.MaxKelvin > 0 {
+				payload["max_kelvin"] = options.MaxKelvin
+			}
+		} else {
+			if options.MinMireds > 0 {
+				payload["min_mireds"] = options.MinMireds
+			}
+			if options.MaxMireds > 0 {
+				payload["max_mireds"] = options.MaxMireds
+			}
+		}
+	}
+	if options.White_scale > 0 {
+		payload["white_scale"] = options.White_scale
+	}
+	if options.SupportsFlash {
+		payload["flash"] = true
+		if options.Flash_time_short > 0 {
+			payload["flash_time_short"] = options.Flash_time_short
+		}
+		if options.Flash_time_long > 0 {
+			payload["flash_time_long"] = options.Flash_time_long
+		}
+	}
+	if options.SupportsEffects {
+		payload["effect"] = true
+		if len(options.EffectList) > 0 {
+			payload["effect_list"] = options.EffectList
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterLight 注册一个 JSON schema 灯光实体
+// handler - 处理解码后的命令，成功后回写最新状态
+// stateHandler - 返回当前灯光状态的可选回调，为 nil 时以命令内容乐观回写
+func (c *MQTTClient) RegisterLight(entity MqttEntity,
+	handler LightCommandHandler,
+	stateHandler func() LightState) {
+
+	entity.Component = "light"
+	options, _ := entity.ExternalOptions.(*LightOptions)
+	var commandHandler mqtt.MessageHandler
+	if handler != nil {
+		commandHandler = func(client mqtt.Client, msg mqtt.Message) {
+			cmd, err := DecodeLightCommand(msg.Payload(), options)
+			if err != nil {
+				fmt.Println(entity.Name, err)
+				return
+			}
+			if err := handler(cmd); err != nil {
+				fmt.Println("灯光命令执行失败:", entity.Name, err)
+				return
+			}
+			state := StateFromCommand(cmd)
+			if stateHandler != nil {
+				state = stateHandler()
+			}
+			if err := c.PublishLightState(entity, state); err != nil {
+				fmt.Println("灯光状态发布失败:", entity.Name, err)
+			}
+		}
+	}
+	var stateFn func() interface{}
+	if stateHandler != nil {
+		stateFn = func() interface{} { return stateHandler() }
+	}
+	c.registerSensor(entity, commandHandler, stateFn, func(entity MqttEntity) error {
+		c.RegisterLight(entity, handler, stateHandler)
+		return nil
+	})
+}
+
+// PublishLightState 立即发布灯光状态
+func (c *MQTTClient) PublishLightState(entity MqttEntity, state LightState) error {
+	if c.client == nil || !c.client.IsConnected() {
+		return fmt.Errorf("MQTT未连接")
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	topic := c.topics.entity("light", c.nodeID(entity.Device), entity.Name, "state")
+	token := c.client.Publish(topic, 1, true, data)
+	token.Wait()
+	return token.Error()
+}
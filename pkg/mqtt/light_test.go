@@ -0,0 +1,48 @@
+package mqtt
+
+import "testing"
+
+func TestDecodeLightCommandColorMode(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"state":"ON"}`, ""},
+		{`{"state":"ON","brightness":128}`, ""},
+		{`{"state":"ON","color":{"r":255,"g":0,"b":0}}`, ColorModeRGB},
+		{`{"state":"ON","color":{"r":255,"g":0,"b":0,"w":10}}`, ColorModeRGBW},
+		{`{"state":"ON","color":{"r":255,"g":0,"b":0,"c":5,"w":10}}`, ColorModeRGBWW},
+		{`{"state":"ON","color":{"h":120,"s":50}}`, ColorModeHS},
+		{`{"state":"ON","color":{"x":0.3,"y":0.4}}`, ColorModeXY},
+		{`{"state":"ON","color_temp":300}`, ColorModeColorTemp},
+		{`{"state":"ON","white":200}`, ColorModeWhite},
+	}
+	for _, tt := range tests {
+		cmd, err := DecodeLightCommand([]byte(tt.payload), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.payload, err)
+		}
+		if cmd.ColorMode != tt.want {
+			t.Errorf("%s: ColorMode = %q, want %q", tt.payload, cmd.ColorMode, tt.want)
+		}
+		if state := StateFromCommand(cmd); state.ColorMode != tt.want || state.White != cmd.White || state.Color != cmd.Color {
+			t.Errorf("%s: StateFromCommand = %+v", tt.payload, state)
+		}
+	}
+}
+
+func TestDecodeLightCommandInvalid(t *testing.T) {
+	for _, payload := range []string{
+		`not json`,
+		`{"state":"DIM"}`,
+		`{"state":"ON","brightness":256}`,
+		`{"state":"ON","brightness":-1}`,
+	} {
+		if _, err := DecodeLightCommand([]byte(payload), nil); err == nil {
+			t.Errorf("%s: 应返回错误", payload)
+		}
+	}
+	if _, err := DecodeLightCommand([]byte(`{"state":"ON","brightness":1000}`), &LightOptions{Brightness_scale: 1000}); err != nil {
+		t.Errorf("brightness_scale 内的亮度: %v", err)
+	}
+}
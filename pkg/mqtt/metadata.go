@@ -0,0 +1,87 @@
+package mqtt
+
+import (
+	"log"
+	"time"
+)
+
+// 实体分类
+const (
+	EntityCategoryDiagnostic = "diagnostic" // 诊断信息
+	EntityCategoryConfig     = "config"     // 配置项，只适用于可控制的实体
+)
+
+// 传感器状态类型，用于长期统计
+const (
+	StateClassMeasurement     = "measurement"
+	StateClassTotal           = "total"
+	StateClassTotalIncreasing = "total_increasing"
+)
+
+// 只读实体，不能作为 config 分类
+var readOnlyComponents = map[string]bool{"sensor": true, "binary_sensor": true}
+
+// applyMetadata 写入实体的通用元数据，不适用于该组件类型的字段忽略并记录日志
+func applyMetadata(payload map[string]any, entity MqttEntity) {
+	component := entity.component()
+	invalid := func(field string, value any) {
+		log.Println("实体", entity.Name, "的", component, "不支持", field, value)
+	}
+	if entity.Icon != "" {
+		payload["icon"] = entity.Icon
+	}
+	switch entity.EntityCategory {
+	case "":
+	case EntityCategoryDiagnostic:
+		payload["entity_category"] = entity.EntityCategory
+	case EntityCategoryConfig:
+		if readOnlyComponents[component] {
+			invalid("entity_category", entity.EntityCategory)
+		} else {
+			payload["entity_category"] = entity.EntityCategory
+		}
+	default:
+		invalid("entity_category", entity.EntityCategory)
+	}
+	if entity.StateClass != "" {
+		switch {
+		case component != "sensor":
+			invalid("state_class", entity.StateClass)
+		case entity.StateClass == StateClassMeasurement || entity.StateClass == StateClassTotal ||
+			entity.StateClass == StateClassTotalIncreasing:
+			payload["state_class"] = entity.StateClass
+		default:
+			invalid("state_class", entity.StateClass)
+		}
+	}
+	if entity.SuggestedDisplayPrecision != nil {
+		if component == "sensor" {
+			payload["suggested_display_precision"] = *entity.SuggestedDisplayPrecision
+		} else {
+			invalid("suggested_display_precision", *entity.SuggestedDisplayPrecision)
+		}
+	}
+	if entity.ExpireAfter > 0 {
+		if readOnlyComponents[component] {
+			payload["expire_after"] = int((entity.ExpireAfter + time.Second - 1) / time.Second)
+		} else {
+			invalid("expire_after", entity.ExpireAfter)
+		}
+	}
+	if entity.ForceUpdate {
+		if readOnlyComponents[component] {
+			payload["force_update"] = true
+		} else {
+			invalid("force_update", true)
+		}
+	}
+	if entity.EnabledByDefault != nil {
+		payload["enabled_by_default"] = *entity.EnabledByDefault
+	}
+	if entity.JSONAttributesTopic != "" {
+		payload["json_attributes_topic"] = entity.JSONAttributesTopic
+	}
+	if entity.JSONAttributesTemplate != "" {
+		payload["json_attributes_template"] = entity.JSONAttributesTemplate
+	}
+}
@@ -0,0 +1,170 @@
+package mqtt
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/net"
+)
+
+// CollectorNetwork 按网络接口发布速率、流量、错误和连接状态的内置采集器
+const CollectorNetwork = "network"
+
+// NetworkFilter 按接口名筛选网络接口，模式使用 path.Match 语法(如 eth*)
+// Include 为空时包含全部，Exclude 优先于 Include
+type NetworkFilter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"` // 为 nil 时排除 DefaultNetworkExclude
+}
+
+// DefaultNetworkExclude 默认排除回环和容器虚拟接口
+var DefaultNetworkExclude = []string{"lo", "lo0", "veth*", "docker*", "br-*", "virbr*"}
+
+func (f NetworkFilter) match(name string) bool {
+	exclude := f.Exclude
+	if exclude == nil {
+		exclude = DefaultNetworkExclude
+	}
+	if matchAny(exclude, name) {
+		return false
+	}
+	return len(f.Include) == 0 || matchAny(f.Include, name)
+}
+
+// networkInfo 网络接口的属性
+type networkInfo struct {
+	MAC  string   `json:"mac"`
+	MTU  int      `json:"mtu"`
+	IPv4 []string `json:"ipv4"`
+	IPv6 []string `json:"ipv6"`
+}
+
+// linkUp 接口是否已连接，Linux 下使用 operstate，其他系统使用 up 标志
+func linkUp(iface net.InterfaceStat) bool {
+	if state, err := os.ReadFile(filepath.Join("/sys/class/net", iface.Name, "operstate")); err == nil {
+		switch strings.TrimSpace(string(state)) {
+		case "up":
+			return true
+		case "unknown":
+			// 部分虚拟接口(如 tun)没有载波状态
+		default:
+			return false
+		}
+	}
+	return slices.Contains(iface.Flags, "up")
+}
+
+// newNetworkCollector 每个网络接口发布速率、流量、错误、丢包和连接状态
+func newNetworkCollector(filter NetworkFilter) *dynamicCollector {
+	var counters counterRates
+	return newDynamicCollector(CollectorNetwork, func() (map[string]any, []MqttEntity, error) {
+		stats, err := net.IOCounters(true)
+		if err != nil {
+			return nil, nil, err
+		}
+		interfaces, err := net.Interfaces()
+		if err != nil {
+			return nil, nil, err
+		}
+		byName := map[string]net.IOCountersStat{}
+		for _, c := range stats {
+			byName[c.Name] = c
+		}
+		sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+
+		counters.begin(time.Now())
+		defer counters.end()
+		data := map[string]any{}
+		var entities []MqttEntity
+		for _, iface := range interfaces {
+			cur, ok := byName[iface.Name]
+			if !ok || !filter.match(iface.Name) {
+				continue
+			}
+			id := "net_" + entityID(iface.Name)
+
+			data[id+"_info"] = newNetworkInfo(iface)
+			data[id+"_link"] = "OFF"
+			if linkUp(iface) {
+				data[id+"_link"] = "ON"
+			}
+			data[id+"_rx_total"] = cur.BytesRecv
+			data[id+"_tx_total"] = cur.BytesSent
+			data[id+"_errors"] = cur.Errin + cur.Errout
+			data[id+"_drops"] = cur.Dropin + cur.Dropout
+
+			if rates, ok := counters.rates(iface.Name, map[string]uint64{"rx": cur.BytesRecv, "tx": cur.BytesSent}); ok {
+				data[id+"_rx"] = round1(rates["rx"])
+				data[id+"_tx"] = round1(rates["tx"])
+			}
+			entities = append(entities, networkEntities(id, iface.Name, counters.ready(iface.Name))...)
+		}
+		return data, entities, nil
+	})
+}
+
+// newNetworkInfo 接口的 MAC、MTU 和地址
+func newNetworkInfo(iface net.InterfaceStat) networkInfo {
+	info := networkInfo{MAC: iface.HardwareAddr, MTU: iface.MTU, IPv4: []string{}, IPv6: []string{}}
+	for _, addr := range iface.Addrs {
+		prefix, err := netip.ParsePrefix(addr.Addr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			info.IPv4 = append(info.IPv4, addr.Addr)
+		} else {
+			info.IPv6 = append(info.IPv6, addr.Addr)
+		}
+	}
+	return info
+}
+
+// networkEntities 接口的实体，rates 为 false 时还没有速率，不包含速率传感器
+func networkEntities(id, name string, rates bool) []MqttEntity {
+	sensor := func(suffix, description, deviceClass, unit, stateClass string) MqttEntity {
+		return MqttEntity{
+			Name:              id + "_" + suffix,
+			Description:       name + " " + description,
+			Component:         "sensor",
+			DeviceClass:       deviceClass,
+			UnitOfMeasurement: unit,
+			ValueTemplate:     "value_json." + id + "_" + suffix,
+			StateClass:        stateClass,
+		}
+	}
+	rx := sensor("rx", "Receive", "data_rate", "B/s", StateClassMeasurement)
+	tx := sensor("tx", "Transmit", "data_rate", "B/s", StateClassMeasurement)
+	rxTotal := sensor("rx_total", "Received", "data_size", "B", StateClassTotalIncreasing)
+	txTotal := sensor("tx_total", "Transmitted", "data_size", "B", StateClassTotalIncreasing)
+	rx.OtherConfig = map[string]any{"suggested_unit_of_measurement": "kB/s"}
+	tx.OtherConfig = map[string]any{"suggested_unit_of_measurement": "kB/s"}
+	rxTotal.OtherConfig = map[string]any{"suggested_unit_of_measurement": "GiB"}
+	txTotal.OtherConfig = map[string]any{"suggested_unit_of_measurement": "GiB"}
+	errors := sensor("errors", "Errors", "", "", StateClassTotalIncreasing)
+	drops := sensor("drops", "Drops", "", "", StateClassTotalIncreasing)
+	errors.EntityCategory = EntityCategoryDiagnostic
+	drops.EntityCategory = EntityCategoryDiagnostic
+	errors.Icon = "mdi:alert-circle-outline"
+	drops.Icon = "mdi:package-variant-remove"
+	entities := []MqttEntity{
+		rxTotal, txTotal, errors, drops,
+		{
+			Name:                   id + "_link",
+			Description:            name + " Link",
+			Component:              "binary_sensor",
+			DeviceClass:            BinaryDeviceClassConnectivity,
+			ValueTemplate:          "value_json." + id + "_link",
+			JSONAttributesTemplate: "{{ value_json." + id + "_info | tojson }}",
+		},
+	}
+	if rates {
+		entities = append([]MqttEntity{rx, tx}, entities...)
+	}
+	return entities
+}
@@ -0,0 +1,210 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PublishPolicy 实体的状态发布策略，为 nil 时每个周期都发布
+// Deadband 和 DeadbandPercent 均为0时，只要值发生变化就发布；
+// 设置了死区时，数值变化超过任一死区才发布，非数值按是否相等判断
+type PublishPolicy struct {
+	Deadband        float64       `json:"deadband"`         // 绝对死区
+	DeadbandPercent float64       `json:"deadband_percent"` // 相对死区(%)，相对上次发布的值
+	Heartbeat       time.Duration `json:"heartbeat"`        // 最长发布间隔，到期后即使未变化也强制发布，0 表示不强制
+}
+
+// exceeds 判断新值相对上次发布的值是否需要发布
+func (p *PublishPolicy) exceeds(last, current any) bool {
+	lf, lok := toFloat(last)
+	cf, cok := toFloat(current)
+	if !lok || !cok {
+		return !sameValue(last, current)
+	}
+	diff := math.Abs(cf - lf)
+	if p.Deadband <= 0 && p.DeadbandPercent <= 0 {
+		return diff != 0
+	}
+	if p.Deadband > 0 && diff > p.Deadband {
+		return true
+	}
+	if p.DeadbandPercent > 0 {
+		if lf == 0 {
+			return diff != 0
+		}
+		if diff/math.Abs(lf)*100 > p.DeadbandPercent {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sameValue 非数值按 JSON 序列化结果比较
+func sameValue(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// templateKey 从 value_json.xxx 形式的模板中提取字段名
+func templateKey(template string) string {
+	template = strings.TrimSpace(template)
+	template = strings.TrimPrefix(template, "{{")
+	template = strings.TrimSuffix(template, "}}")
+	template = strings.TrimSpace(template)
+	if !strings.HasPrefix(template, "value_json.") {
+		return ""
+	}
+	key := strings.TrimPrefix(template, "value_json.")
+	if i := strings.IndexAny(key, " |.["); i >= 0 {
+		key = key[:i]
+	}
+	return key
+}
+
+// applyPolicies 为未设置发布策略的实体填充 MQTTConfig.PublishPolicies 中的配置
+func (c *MQTTClient) applyPolicies(entities []MqttEntity) []MqttEntity {
+	result := make([]MqttEntity, len(entities))
+	for i, entity := range entities {
+		if entity.Policy == nil {
+			entity.Policy = c.publishPolicies[entity.Name]
+		}
+		result[i] = entity
+	}
+	return result
+}
+
+// newGate 创建发布判定并按任务键登记到客户端，便于重新发布时统一重置
+func (c *MQTTClient) newGate(key string, entities []MqttEntity, single bool) *publishGate {
+	gate := newPublishGate(c.applyPolicies(entities), single)
+	c.mu.Lock()
+	if gate != nil {
+		c.gates[key] = gate
+	} else {
+		delete(c.gates, key)
+	}
+	c.mu.Unlock()
+	return gate
+}
+
+// resetGates 清除所有发布判定的记录，下次采集必定发布
+func (c *MQTTClient) resetGates() {
+	c.mu.Lock()
+	gates := make([]*publishGate, 0, len(c.gates))
+	for _, gate := range c.gates {
+		gates = append(gates, gate)
+	}
+	c.mu.Unlock()
+	for _, gate := range gates {
+		gate.reset()
+	}
+}
+
+// publishGate 记录上次发布的值，按各字段的发布策略决定是否发布
+type publishGate struct {
+	mu          sync.Mutex
+	policies    map[string]*PublishPolicy // 字段名 -> 发布策略
+	always      bool                      // 存在未设置策略的字段时每次都发布
+	last        map[string]any
+	lastPublish time.Time
+}
+
+// newPublishGate 根据实体列表创建发布判定，没有任何实体设置策略时返回 nil
+// 单值状态（非 JSON 对象）使用空字符串作为字段名
+func newPublishGate(entities []MqttEntity, single bool) *publishGate {
+	gate := &publishGate{policies: map[string]*PublishPolicy{}}
+	for _, entity := range entities {
+		if entity.Policy == nil {
+			gate.always = true
+			continue
+		}
+		key := ""
+		if !single {
+			key = templateKey(entity.ValueTemplate)
+		}
+		gate.policies[key] = entity.Policy
+	}
+	if len(gate.policies) == 0 {
+		return nil
+	}
+	return gate
+}
+
+// allow 判断本次采集的值是否需要发布，需要时记录为最新发布值
+func (g *publishGate) allow(values map[string]any, now time.Time) bool {
+	if g == nil {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	publish := g.always || g.last == nil
+	for key, policy := range g.policies {
+		if publish {
+			break
+		}
+		if policy.Heartbeat > 0 && now.Sub(g.lastPublish) >= policy.Heartbeat {
+			publish = true
+			break
+		}
+		last, ok := g.last[key]
+		if !ok || policy.exceeds(last, values[key]) {
+			publish = true
+		}
+	}
+	if !publish {
+		return false
+	}
+	g.last = make(map[string]any, len(g.policies))
+	for key := range g.policies {
+		g.last[key] = values[key]
+	}
+	g.lastPublish = now
+	return true
+}
+
+// reset 清除记录，下次判定必定发布
+func (g *publishGate) reset() {
+	g.mu.Lock()
+	g.last = nil
+	g.mu.Unlock()
+}
@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPublishPolicyExceeds(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        PublishPolicy
+		last, current any
+		want          bool
+	}{
+		{"无死区未变化", PublishPolicy{}, 10.0, 10.0, false},
+		{"无死区变化", PublishPolicy{}, 10.0, 10.1, true},
+		{"不同数值类型", PublishPolicy{}, 10, 10.0, false},
+		{"json.Number", PublishPolicy{}, json.Number("3"), 3, false},
+		{"绝对死区内", PublishPolicy{Deadband: 0.5}, 10.0, 10.5, false},
+		{"超过绝对死区", PublishPolicy{Deadband: 0.5}, 10.0, 10.6, true},
+		{"绝对死区双向", PublishPolicy{Deadband: 0.5}, 10.0, 9.4, true},
+		{"相对死区内", PublishPolicy{DeadbandPercent: 10}, 50.0, 54.0, false},
+		{"超过相对死区", PublishPolicy{DeadbandPercent: 10}, 50.0, 56.0, true},
+		{"相对死区上次为0", PublishPolicy{DeadbandPercent: 10}, 0.0, 0.1, true},
+		{"相对死区上次为0未变化", PublishPolicy{DeadbandPercent: 10}, 0, 0, false},
+		{"负数相对死区", PublishPolicy{DeadbandPercent: 10}, -50.0, -54.0, false},
+		{"超过任一死区", PublishPolicy{Deadband: 5, DeadbandPercent: 1}, 100.0, 102.0, true},
+		{"两个死区内", PublishPolicy{Deadband: 5, DeadbandPercent: 10}, 100.0, 102.0, false},
+		{"字符串未变化", PublishPolicy{Deadband: 1}, "ON", "ON", false},
+		{"字符串变化", PublishPolicy{Deadband: 1}, "ON", "OFF", true},
+		{"对象按 JSON 比较", PublishPolicy{}, map[string]any{"a": 1}, map[string]any{"a": 1}, false},
+		{"数值变为字符串", PublishPolicy{}, 1.0, "1", true},
+		{"nil 未变化", PublishPolicy{}, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.exceeds(tt.last, tt.current); got != tt.want {
+				t.Errorf("exceeds(%v, %v) = %v, want %v", tt.last, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateKey(t *testing.T) {
+	tests := map[string]string{
+		"value_json.cpu_usage":                  "cpu_usage",
+		"{{ value_json.mem_usage }}":            "mem_usage",
+		"value_json.temp | round(1)":            "temp",
+		"value_json.disk.used":                  "disk",
+		"value_json.cores[0]":                   "cores",
+		"  value_json.x  ":                      "x",
+		"value":                                 "",
+		"{{ value_json['power_status'] }}":      "",
+		"{{ (value_json.a + value_json.b) }}":   "",
+		"value_json.cpu_cores | tojson":         "cpu_cores",
+		"{{ value_json.load1|float(0) }}":       "load1",
+		"{{value_json.swap_usage}}":             "swap_usage",
+		"states('sensor.cpu') | float":          "",
+		"value_json.net_eth0_rx | default(0.0)": "net_eth0_rx",
+	}
+	for template, want := range tests {
+		if got := templateKey(template); got != want {
+			t.Errorf("templateKey(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestNewPublishGate(t *testing.T) {
+	policy := &PublishPolicy{Deadband: 1}
+	if gate := newPublishGate([]MqttEntity{{Name: "cpu", ValueTemplate: "value_json.cpu"}}, false); gate != nil {
+		t.Fatalf("没有策略时应返回 nil，实际 %+v", gate)
+	}
+	var nilGate *publishGate
+	if !nilGate.allow(map[string]any{"cpu": 1}, time.Now()) {
+		t.Fatal("nil 判定应始终发布")
+	}
+	gate := newPublishGate([]MqttEntity{{Name: "temp", ValueTemplate: "value_json.temp", Policy: policy}}, true)
+	if _, ok := gate.policies[""]; !ok {
+		t.Fatalf("单值状态应使用空字段名，实际 %v", gate.policies)
+	}
+}
+
+// step 一次采集及期望的判定结果
+type step struct {
+	after  time.Duration // 距离开始的时间
+	values map[string]any
+	want   bool
+}
+
+func TestPublishGateAllow(t *testing.T) {
+	mem := func(policy *PublishPolicy) MqttEntity {
+		return MqttEntity{Name: "memory", ValueTemplate: "value_json.mem_usage", Policy: policy}
+	}
+	// 同一采集器中未设置策略的实体
+	swap := MqttEntity{Name: "swap", ValueTemplate: "value_json.swap_usage"}
+	cached := MqttEntity{Name: "memory_cached", ValueTemplate: "value_json.mem_cached"}
+
+	tests := []struct {
+		name     string
+		entities []MqttEntity
+		steps    []step
+	}{
+		{
+			name:     "首次必定发布，未变化不发布",
+			entities: []MqttEntity{mem(&PublishPolicy{})},
+			steps: []step{
+				{0, map[string]any{"mem_usage": 40.0}, true},
+				{time.Second, map[string]any{"mem_usage": 40.0}, false},
+				{2 * time.Second, map[string]any{"mem_usage": 41.0}, true},
+			},
+		},
+		{
+			name:     "死区与上次发布的值比较",
+			entities: []MqttEntity{mem(&PublishPolicy{Deadband: 1})},
+			steps: []step{
+				{0, map[string]any{"mem_usage": 40.0}, true},
+				{time.Second, map[string]any{"mem_usage": 40.8}, false},
+				// 相对上次发布的 40 而不是上次采集的 40.8
+				{2 * time.Second, map[string]any{"mem_usage": 41.5}, true},
+				{3 * time.Second, map[string]any{"mem_usage": 41.0}, false},
+			},
+		},
+		{
+			name:     "心跳到期强制发布",
+			entities: []MqttEntity{mem(&PublishPolicy{Deadband: 5, Heartbeat: time.Minute})},
+			steps: []step{
+				{0, map[string]any{"mem_usage": 40.0}, true},
+				{30 * time.Second, map[string]any{"mem_usage": 40.0}, false},
+				{time.Minute, map[string]any{"mem_usage": 40.0}, true},
+				{90 * time.Second, map[string]any{"mem_usage": 40.0}, false},
+			},
+		},
+		{
+			name:     "存在未设置策略的实体时每次都发布",
+			entities: []MqttEntity{mem(&PublishPolicy{DeadbandPercent: 5}), swap, cached},
+			steps: []step{
+				{0, map[string]any{"mem_usage": 40.0, "swap_usage": 1.0, "mem_cached": 100}, true},
+				{time.Second, map[string]any{"mem_usage": 40.0, "swap_usage": 1.0, "mem_cached": 100}, true},
+			},
+		},
+		{
+			name: "任一字段超过死区即发布",
+			entities: []MqttEntity{
+				mem(&PublishPolicy{Deadband: 5}),
+				{Name: "swap", ValueTemplate: "value_json.swap_usage", Policy: &PublishPolicy{}},
+			},
+			steps: []step{
+				{0, map[string]any{"mem_usage": 40.0, "swap_usage": 1.0}, true},
+				{time.Second, map[string]any{"mem_usage": 42.0, "swap_usage": 1.0}, false},
+				{2 * time.Second, map[string]any{"mem_usage": 42.0, "swap_usage": 2.0}, true},
+			},
+		},
+		{
+			name:     "字段缺失后出现",
+			entities: []MqttEntity{mem(&PublishPolicy{Deadband: 1})},
+			steps: []step{
+				{0, map[string]any{}, true},
+				{time.Second, map[string]any{}, false},
+				{2 * time.Second, map[string]any{"mem_usage": 40.0}, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := newPublishGate(tt.entities, false)
+			start := time.Now()
+			for i, s := range tt.steps {
+				if got := gate.allow(s.values, start.Add(s.after)); got != s.want {
+					t.Fatalf("第 %d 次采集 %v: allow = %v, want %v", i, s.values, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishGateReset(t *testing.T) {
+	gate := newPublishGate([]MqttEntity{{Name: "cpu", ValueTemplate: "value_json.cpu", Policy: &PublishPolicy{}}}, false)
+	now := time.Now()
+	values := map[string]any{"cpu": 10.0}
+	if !gate.allow(values, now) || gate.allow(values, now) {
+		t.Fatal("首次应发布，未变化不应发布")
+	}
+	gate.reset()
+	if !gate.allow(values, now) {
+		t.Fatal("重置后应发布")
+	}
+}
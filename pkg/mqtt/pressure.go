@@ -0,0 +1,46 @@
+package mqtt
+
+import "github.com/LanSilence/hamqtt/internal/system"
+
+// CollectorPressure 发布 Linux PSI(/proc/pressure) 的内置采集器
+const CollectorPressure = "pressure"
+
+// newPressureCollector 每个资源的 some/full 各发布 avg10/avg60/avg300 三个传感器，
+// 默认只启用 avg10，内核未开启 PSI 时没有实体
+func newPressureCollector() *dynamicCollector {
+	return newDynamicCollector(CollectorPressure, func() (map[string]any, []MqttEntity, error) {
+		data := map[string]any{}
+		var entities []MqttEntity
+		for _, pressure := range system.ReadPressure("") {
+			id := "psi_" + pressure.Resource + "_" + pressure.Kind
+			data[id+"_avg10"] = pressure.Avg10
+			data[id+"_avg60"] = pressure.Avg60
+			data[id+"_avg300"] = pressure.Avg300
+			entities = append(entities, pressureEntities(id, pressure)...)
+		}
+		return data, entities, nil
+	})
+}
+
+func pressureEntities(id string, pressure system.Pressure) []MqttEntity {
+	precision := 2
+	disabled := false
+	var entities []MqttEntity
+	for _, window := range []string{"avg10", "avg60", "avg300"} {
+		entity := MqttEntity{
+			Name:                      id + "_" + window,
+			Description:               "Pressure " + pressure.Resource + " " + pressure.Kind + " " + window,
+			Component:                 "sensor",
+			UnitOfMeasurement:         "%",
+			ValueTemplate:             "value_json." + id + "_" + window,
+			StateClass:                StateClassMeasurement,
+			SuggestedDisplayPrecision: &precision,
+			Icon:                      "mdi:speedometer",
+		}
+		if window != "avg10" {
+			entity.EnabledByDefault = &disabled
+		}
+		entities = append(entities, entity)
+	}
+	return entities
+}
@@ -0,0 +1,127 @@
+package mqtt
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultPublishInterval 未配置发布间隔时使用的默认值
+const DefaultPublishInterval = 2 * time.Second
+
+// scheduledJob 定时发布任务
+type scheduledJob struct {
+	interval time.Duration
+	next     time.Time
+	run      func()
+}
+
+// scheduler 所有周期发布任务共用的调度器，一个 goroutine 负责计时，
+// 到期任务各自在新的 goroutine 中执行，慢任务不会阻塞其他任务
+type scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	running map[string]bool // 正在执行的任务，同一任务不会重叠执行
+	wake    chan struct{}
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		jobs:    map[string]*scheduledJob{},
+		running: map[string]bool{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// add 添加或替换任务，任务会被立即执行一次
+func (s *scheduler) add(key string, interval time.Duration, run func()) {
+	if interval <= 0 {
+		interval = DefaultPublishInterval
+	}
+	s.mu.Lock()
+	s.jobs[key] = &scheduledJob{interval: interval, next: time.Now(), run: run}
+	s.mu.Unlock()
+	s.notify()
+}
+
+// remove 删除任务
+func (s *scheduler) remove(key string) {
+	s.mu.Lock()
+	delete(s.jobs, key)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// triggerAll 令所有任务立即执行一次
+func (s *scheduler) triggerAll() {
+	now := time.Now()
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		job.next = now
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due 取出到期任务并安排下次执行时间，同时返回距离下一个任务的等待时长
+// 上次执行尚未结束的任务跳过本次
+func (s *scheduler) due(now time.Time) (map[string]func(), time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := map[string]func(){}
+	wait := time.Minute
+	for key, job := range s.jobs {
+		if !job.next.After(now) {
+			if !s.running[key] {
+				s.running[key] = true
+				runs[key] = job.run
+			}
+			job.next = now.Add(job.interval)
+		}
+		if d := job.next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return runs, wait
+}
+
+// exec 执行任务并清除执行标记
+func (s *scheduler) exec(key string, run func()) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, key)
+		s.mu.Unlock()
+	}()
+	run()
+}
+
+// run 调度循环，直到 stop 关闭
+func (s *scheduler) run(stop <-chan struct{}) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+		runs, wait := s.due(time.Now())
+		for key, run := range runs {
+			go s.exec(key, run)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
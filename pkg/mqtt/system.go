@@ -0,0 +1,125 @@
+package mqtt
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/process"
+)
+
+// CollectorSystem 负载、运行时间、进程数和登录用户数的内置采集器
+const CollectorSystem = "system"
+
+// systemInterval 系统信息变化较慢，默认30秒采集一次
+const systemInterval = 30 * time.Second
+
+// newSystemCollector 只发布当前平台能采集到的指标，实体列表随采集结果生成
+func newSystemCollector() *dynamicCollector {
+	all := systemEntities()
+	col := newDynamicCollector(CollectorSystem, func() (map[string]any, []MqttEntity, error) {
+		data, err := collectSystem()
+		if err != nil {
+			return nil, nil, err
+		}
+		var entities []MqttEntity
+		for _, entity := range all {
+			if _, ok := data[templateKey(entity.ValueTemplate)]; ok {
+				entities = append(entities, entity)
+			}
+		}
+		return data, entities, nil
+	})
+	col.interval = systemInterval
+	return col
+}
+
+func systemEntities() []MqttEntity {
+	precision := 2
+	diagnostic := func(entity MqttEntity) MqttEntity {
+		entity.Component = "sensor"
+		entity.EntityCategory = EntityCategoryDiagnostic
+		return entity
+	}
+	loadAvg := func(name, description string) MqttEntity {
+		return diagnostic(MqttEntity{
+			Name:                      name,
+			Description:               description,
+			ValueTemplate:             "value_json." + name,
+			StateClass:                StateClassMeasurement,
+			SuggestedDisplayPrecision: &precision,
+			Icon:                      "mdi:gauge",
+		})
+	}
+	count := func(name, description, icon string) MqttEntity {
+		return diagnostic(MqttEntity{
+			Name:          name,
+			Description:   description,
+			ValueTemplate: "value_json." + name,
+			StateClass:    StateClassMeasurement,
+			Icon:          icon,
+		})
+	}
+	return []MqttEntity{
+		loadAvg("load_1", "Load (1m)"),
+		loadAvg("load_5", "Load (5m)"),
+		loadAvg("load_15", "Load (15m)"),
+		diagnostic(MqttEntity{
+			Name:              "uptime",
+			Description:       "Uptime",
+			DeviceClass:       "duration",
+			UnitOfMeasurement: "s",
+			ValueTemplate:     "value_json.uptime",
+			OtherConfig:       map[string]any{"suggested_unit_of_measurement": "d"},
+		}),
+		diagnostic(MqttEntity{
+			Name:          "boot_time",
+			Description:   "Last Boot",
+			DeviceClass:   "timestamp",
+			ValueTemplate: "value_json.boot_time",
+		}),
+		count("processes", "Processes", "mdi:application-cog"),
+		count("processes_running", "Running Processes", "mdi:run"),
+		count("processes_zombie", "Zombie Processes", "mdi:skull-outline"),
+		count("users", "Logged-in Users", "mdi:account-multiple"),
+	}
+}
+
+// collectSystem 部分平台不支持的指标(如 Windows 的负载)不发布
+func collectSystem() (map[string]any, error) {
+	data := map[string]any{}
+	if avg, err := load.Avg(); err == nil {
+		data["load_1"] = avg.Load1
+		data["load_5"] = avg.Load5
+		data["load_15"] = avg.Load15
+	}
+	if uptime, err := host.Uptime(); err == nil {
+		data["uptime"] = uptime
+	}
+	if boot, err := host.BootTime(); err == nil {
+		data["boot_time"] = time.Unix(int64(boot), 0).UTC().Format(time.RFC3339)
+	}
+	if procs, err := process.Processes(); err == nil {
+		running, zombie := 0, 0
+		for _, p := range procs {
+			// 进程可能已经退出
+			status, err := p.Status()
+			if err != nil {
+				continue
+			}
+			switch status {
+			case "R":
+				running++
+			case "Z":
+				zombie++
+			}
+		}
+		data["processes"] = len(procs)
+		data["processes_running"] = running
+		data["processes_zombie"] = zombie
+	}
+	if users, err := host.Users(); err == nil {
+		data["users"] = len(users)
+	}
+	return data, nil
+}
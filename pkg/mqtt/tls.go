@@ -0,0 +1,55 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// useTLS 是否需要为连接配置 TLS
+// paho 只对加密协议使用 TLS 参数，设置了 TLS 选项但服务器地址不是加密协议时返回错误，避免静默使用明文连接
+func (cfg MQTTConfig) useTLS() (bool, error) {
+	secure := false
+	for _, scheme := range []string{"ssl://", "tls://", "mqtts://", "tcps://", "wss://"} {
+		if strings.HasPrefix(cfg.Server, scheme) {
+			secure = true
+		}
+	}
+	options := cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" || cfg.ServerName != "" || cfg.InsecureSkipVerify
+	if options && !secure {
+		return false, fmt.Errorf("设置了TLS选项，但服务器地址不是加密协议(如 ssl://): %s", cfg.Server)
+	}
+	return secure, nil
+}
+
+// newTLSConfig 根据配置构造 TLS 参数，支持私有 CA 和客户端证书(双向认证)
+func newTLSConfig(cfg MQTTConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("CA证书中没有有效的PEM证书: %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("客户端证书和私钥必须同时配置")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
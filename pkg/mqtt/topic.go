@@ -0,0 +1,84 @@
+package mqtt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultDiscoveryPrefix HomeAssistant 默认的自动发现前缀
+const DefaultDiscoveryPrefix = "homeassistant"
+
+// DefaultNodeID 默认的节点ID模板，与旧版本的主题保持一致
+const DefaultNodeID = "{hostname}{client_id}"
+
+// topicBuilder 统一生成全部 MQTT 主题
+// 自动发现配置始终位于 <discovery_prefix>/ 下；
+// base 为空时状态、命令和可用性主题也位于自动发现前缀下(旧版布局)，
+// 否则使用 <base>/<node_id>/... 布局
+type topicBuilder struct {
+	discoveryPrefix string
+	base            string
+}
+
+func newTopicBuilder(discoveryPrefix, base string) topicBuilder {
+	if discoveryPrefix = strings.Trim(discoveryPrefix, "/"); discoveryPrefix == "" {
+		discoveryPrefix = DefaultDiscoveryPrefix
+	}
+	return topicBuilder{discoveryPrefix: discoveryPrefix, base: strings.Trim(base, "/")}
+}
+
+// config 实体的自动发现主题 <prefix>/<component>/<node_id>/<object_id>/config
+func (t topicBuilder) config(component, node, name string) string {
+	return t.discoveryPrefix + "/" + component + "/" + node + "/" + name + "/config"
+}
+
+// legacyConfigs 匹配节点全部按实体发布的自动发现主题
+func (t topicBuilder) legacyConfigs(node string) string {
+	return t.discoveryPrefix + "/+/" + node + "/+/config"
+}
+
+// deviceConfig 设备模式下设备的自动发现主题
+func (t topicBuilder) deviceConfig(node string) string {
+	return t.discoveryPrefix + "/device/" + node + "/config"
+}
+
+// birth HomeAssistant 的出生主题
+func (t topicBuilder) birth() string {
+	return t.discoveryPrefix + "/status"
+}
+
+// entity 单个实体的状态/命令主题，suffix 如 state, set
+func (t topicBuilder) entity(component, node, name, suffix string) string {
+	if t.base == "" {
+		return t.discoveryPrefix + "/" + component + "/" + node + "/" + name + "/" + suffix
+	}
+	return t.base + "/" + node + "/" + component + "/" + name + "/" + suffix
+}
+
+// state 同一组件类型实体共用的状态主题
+func (t topicBuilder) state(component, node string) string {
+	if t.base == "" {
+		return t.discoveryPrefix + "/" + component + "/" + node + "/state"
+	}
+	return t.base + "/" + node + "/" + component + "/state"
+}
+
+// availability 设备的可用性主题
+func (t topicBuilder) availability(node string) string {
+	if t.base == "" {
+		return t.discoveryPrefix + "/sensor/" + node + "/status"
+	}
+	return t.base + "/" + node + "/status"
+}
+
+var invalidNodeIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// expandNodeID 展开节点ID模板，支持 {hostname} 和 {client_id}
+// HomeAssistant 只接受 [a-zA-Z0-9_-] 组成的节点ID，其余字符替换为 _
+func expandNodeID(template, hostname, clientID string) string {
+	if template == "" {
+		template = DefaultNodeID
+	}
+	id := strings.NewReplacer("{hostname}", hostname, "{client_id}", clientID).Replace(template)
+	return invalidNodeIDChars.ReplaceAllString(id, "_")
+}
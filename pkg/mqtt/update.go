@@ -0,0 +1,99 @@
+package mqtt
+
+import (
+	"fmt"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// PayloadInstall update 实体的安装命令负载
+const PayloadInstall = "install"
+
+// UpdateState update 实体的状态，整体以 JSON 发布到状态主题
+type UpdateState struct {
+	InstalledVersion string `json:"installed_version"`
+	LatestVersion    string `json:"latest_version,omitempty"` // 为空时 HomeAssistant 视为没有可用更新
+	Title            string `json:"title,omitempty"`
+	ReleaseSummary   string `json:"release_summary,omitempty"`
+	ReleaseURL       string `json:"release_url,omitempty"`
+	InProgress       bool   `json:"in_progress"`
+	Percentage       *int   `json:"update_percentage"` // 安装进度 0~100，nil 表示未知
+}
+
+// UpdateInstaller 执行安装，通过 progress 报告进度(0~100)
+type UpdateInstaller func(progress func(percent int)) error
+
+// updateEntity 记录安装过程中的进度，安装期间的周期状态也会带上进度
+type updateEntity struct {
+	mu         sync.Mutex
+	installing bool
+	percent    *int
+}
+
+func (u *updateEntity) apply(state UpdateState) UpdateState {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.installing {
+		state.InProgress = true
+		state.Percentage = u.percent
+	}
+	return state
+}
+
+// RegisterUpdate 注册 update 实体
+// install - 收到安装命令时在独立的 goroutine 中调用，同一时间只执行一次
+// stateHandler - 返回已安装版本和最新版本
+func (c *MQTTClient) RegisterUpdate(entity MqttEntity, install UpdateInstaller, stateHandler func() UpdateState) error {
+	if install == nil || stateHandler == nil {
+		return fmt.Errorf("update 需要安装回调和状态回调: %s", entity.Name)
+	}
+	if err := c.ensureDevice(entity.Device); err != nil {
+		return err
+	}
+	entity.Component = "update"
+	u := &updateEntity{}
+	publish := func() {
+		if err := c.PublishState(entity, u.apply(stateHandler())); err != nil {
+			fmt.Println("状态发布失败:", entity.Name, err)
+		}
+	}
+	progress := func(percent int) {
+		percent = max(0, min(percent, 100))
+		u.mu.Lock()
+		u.percent = &percent
+		u.mu.Unlock()
+		publish()
+	}
+	commandHandler := func(client mqtt.Client, msg mqtt.Message) {
+		if string(msg.Payload()) != PayloadInstall {
+			return
+		}
+		u.mu.Lock()
+		if u.installing {
+			u.mu.Unlock()
+			fmt.Println("更新正在安装:", entity.Name)
+			return
+		}
+		u.installing, u.percent = true, nil
+		u.mu.Unlock()
+		// 不能在消息回调中阻塞等待安装完成
+		go func() {
+			publish()
+			err := install(progress)
+			u.mu.Lock()
+			u.installing, u.percent = false, nil
+			u.mu.Unlock()
+			if err != nil {
+				fmt.Println("更新安装失败:", entity.Name, err)
+			}
+			publish()
+		}()
+	}
+	c.registerSensor(entity, commandHandler, func() interface{} {
+		return u.apply(stateHandler())
+	}, func(entity MqttEntity) error {
+		return c.RegisterUpdate(entity, install, stateHandler)
+	})
+	return nil
+}